@@ -4,9 +4,14 @@ import (
 	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
 	"github.com/carvalhorr/protoc-gen-mock/stub"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"strings"
 )
 
+// requestJournalSize is the number of calls kept in the request journal. Older calls are dropped.
+const requestJournalSize = 1000
+
 // BootstrapServers starts the gRPC server with the mock services added by serviceRegisterCallback.
 // The REST server for the stub API management is also started.
 // Parameters:
@@ -14,8 +19,13 @@ import (
 // - restPort : the port where the REST server will be started
 // - grpcPort : the port where the gRPC server will be started
 // - serviceRegisterCallback : a function called when the grpc server is ready so that the mock services can be registered
-func BootstrapServers(tmpPath string, restPort uint, grpcPort uint, serviceRegisterCallback func(stubsStore stub.StubsMatcher) grpchandler.MockService) {
+// - options : changes to the configuration of the servers, like WithTLS or WithNotMatchedCode
+func BootstrapServers(tmpPath string, restPort uint, grpcPort uint, serviceRegisterCallback func(stubsStore stub.StubsMatcher) grpchandler.MockService, options ...Option) {
 	setupLogrus()
+	config := configFromEnv()
+	for _, option := range options {
+		option(&config)
+	}
 
 	errorsEngine, err := stub.NewCustomErrorEngine(tmpPath)
 	if err != nil {
@@ -23,10 +33,26 @@ func BootstrapServers(tmpPath string, restPort uint, grpcPort uint, serviceRegis
 	}
 	stub.SetErrorEngine(errorsEngine)
 
+	tlsConfig, err := config.TLS.serverConfig(tmpPath)
+	if err != nil {
+		log.Fatalf("TLS is not configured correctly: %s", err)
+	}
+	if tlsConfig != nil {
+		log.Info("The gRPC and REST servers use TLS")
+	}
+
 	stubsStore := stub.NewInMemoryStubsStore()
-	stubsMatcher := stub.NewStubsMatcher(stubsStore)
+	scenariosStore := stub.NewInMemoryScenariosStore()
+	stubsMatcher := stub.NewStubsMatcher(stubsStore, scenariosStore)
 
 	recordingsStore := stub.NewRecordingsStore()
+	requestJournal := stub.NewInMemoryRequestJournal(requestJournalSize)
+	faultsStore := stub.NewInMemoryFaultsStore()
+	upstreamsStore := stub.NewInMemoryUpstreamsStore()
+	if config.Upstream != nil {
+		log.Infof("Forwarding the calls no stub matches to %s", config.Upstream.ServerAddress)
+		upstreamsStore.Set("", config.Upstream)
+	}
 
 	service := serviceRegisterCallback(stubsMatcher)
 	log.Info("Supported methods: ", strings.Join(service.GetSupportedMethods(), "  |  "))
@@ -34,9 +60,18 @@ func BootstrapServers(tmpPath string, restPort uint, grpcPort uint, serviceRegis
 
 	grpchandler.SetSupportedMockService(service)
 	grpchandler.SetRecordingsStore(recordingsStore)
+	grpchandler.SetRequestJournal(requestJournal)
+	grpchandler.SetFaultsStore(faultsStore)
+	grpchandler.SetUpstreamsStore(upstreamsStore)
+	grpchandler.SetForwardIdleTimeout(config.ForwardIdleTimeout)
+	grpchandler.SetNotMatchedCode(config.NotMatchedCode)
 
-	go StartRESTServer(restPort, CreateRESTControllers(stubsExamples, stubsStore, recordingsStore, service))
-	StarGRPCServer(grpcPort, service)
+	go StartRESTServerWithTLS(restPort, CreateRESTControllers(stubsExamples, stubsStore, stubsMatcher, scenariosStore, recordingsStore, requestJournal, faultsStore, upstreamsStore, service), tlsConfig)
+	if tlsConfig != nil {
+		StarGRPCServer(grpcPort, service, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else {
+		StarGRPCServer(grpcPort, service)
+	}
 }
 
 func setupLogrus() {
@@ -0,0 +1,139 @@
+package bootstrap
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the servers. The upstream and the forward settings are read from environment variables.
+type Config struct {
+	// TLS secures the gRPC and REST servers. Set with WithTLS, or with TLS_CERT_FILE and TLS_KEY_FILE, TLS_CLIENT_CA_FILE for mTLS,
+	// and TLS_SELF_SIGNED=true to generate a self-signed certificate instead.
+	TLS TLSConfig
+	// Upstream receives all the calls no stub matches. Set with UPSTREAM_ADDRESS and UPSTREAM_RECORD=true to record the calls forwarded.
+	// TLS is used with UPSTREAM_TLS=true or when any of UPSTREAM_CA_FILE, UPSTREAM_SERVER_NAME, UPSTREAM_CERT_FILE and UPSTREAM_KEY_FILE is set.
+	// Credentials are sent with UPSTREAM_BEARER_TOKEN or UPSTREAM_FORWARD_AUTHORIZATION=true.
+	// The metadata propagated is limited with the comma separated keys in UPSTREAM_METADATA_ALLOW and UPSTREAM_METADATA_DENY,
+	// and the time the upstream has to reply with UPSTREAM_TIMEOUT, like 2s.
+	Upstream *stub.StubForward
+	// NotMatchedCode is the status code returned when no stub matches a call. Set with WithNotMatchedCode or NOT_MATCHED_CODE, like 12.
+	// Defaults to NotFound.
+	NotMatchedCode codes.Code
+	// ForwardIdleTimeout is how long a connection to an upstream is kept open without being used. Set with FORWARD_IDLE_TIMEOUT, like 30s.
+	ForwardIdleTimeout time.Duration
+}
+
+// Option changes the configuration of the servers
+type Option func(config *Config)
+
+// WithTLS serves the gRPC and REST servers with TLS, or mTLS when a client CA is provided
+func WithTLS(tls TLSConfig) Option {
+	return func(config *Config) {
+		config.TLS = tls
+	}
+}
+
+// WithNotMatchedCode sets the status code returned when no stub matches a call
+func WithNotMatchedCode(code codes.Code) Option {
+	return func(config *Config) {
+		config.NotMatchedCode = code
+	}
+}
+
+func configFromEnv() Config {
+	config := Config{NotMatchedCode: codes.NotFound, TLS: tlsFromEnv()}
+	if code := os.Getenv("NOT_MATCHED_CODE"); code != "" {
+		value, err := strconv.ParseUint(code, 10, 32)
+		if err != nil || value > uint64(codes.Unauthenticated) {
+			log.Fatalf("NOT_MATCHED_CODE is not a valid gRPC status code: %s", code)
+		}
+		config.NotMatchedCode = codes.Code(value)
+	}
+	if address := os.Getenv("UPSTREAM_ADDRESS"); address != "" {
+		config.Upstream = &stub.StubForward{
+			ServerAddress: address,
+			Record:        os.Getenv("UPSTREAM_RECORD") == "true",
+			TLS:           upstreamTLSFromEnv(),
+			Credentials:   upstreamCredentialsFromEnv(),
+			Metadata:      upstreamMetadataFromEnv(),
+			Timeout:       uint32(durationFromEnv("UPSTREAM_TIMEOUT") / time.Millisecond),
+		}
+		if isValid, errMsgs := (&stub.Upstream{Forward: config.Upstream}).IsValid(); !isValid {
+			log.Fatalf("The upstream configuration is not valid: %s", strings.Join(errMsgs, " "))
+		}
+	}
+	config.ForwardIdleTimeout = durationFromEnv("FORWARD_IDLE_TIMEOUT")
+	return config
+}
+
+// durationFromEnv reads a duration like 30s from the environment variable. It is 0 when the variable isn't set.
+func durationFromEnv(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("%s is not a valid duration: %s", name, err)
+	}
+	return duration
+}
+
+// listFromEnv reads the comma separated values of the environment variable
+func listFromEnv(name string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// tlsFromEnv reads the TLS configuration of the servers. They use plaintext when no certificate is configured.
+func tlsFromEnv() TLSConfig {
+	return TLSConfig{
+		CertFile:     os.Getenv("TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("TLS_KEY_FILE"),
+		ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+		SelfSigned:   os.Getenv("TLS_SELF_SIGNED") == "true",
+	}
+}
+
+func upstreamTLSFromEnv() *stub.ForwardTLS {
+	tls := &stub.ForwardTLS{
+		CAFile:     os.Getenv("UPSTREAM_CA_FILE"),
+		ServerName: os.Getenv("UPSTREAM_SERVER_NAME"),
+		CertFile:   os.Getenv("UPSTREAM_CERT_FILE"),
+		KeyFile:    os.Getenv("UPSTREAM_KEY_FILE"),
+	}
+	if os.Getenv("UPSTREAM_TLS") != "true" && *tls == (stub.ForwardTLS{}) {
+		return nil
+	}
+	return tls
+}
+
+func upstreamCredentialsFromEnv() *stub.ForwardCredentials {
+	credentials := &stub.ForwardCredentials{
+		BearerToken:          os.Getenv("UPSTREAM_BEARER_TOKEN"),
+		ForwardAuthorization: os.Getenv("UPSTREAM_FORWARD_AUTHORIZATION") == "true",
+	}
+	if *credentials == (stub.ForwardCredentials{}) {
+		return nil
+	}
+	return credentials
+}
+
+func upstreamMetadataFromEnv() *stub.ForwardMetadata {
+	allow := listFromEnv("UPSTREAM_METADATA_ALLOW")
+	deny := listFromEnv("UPSTREAM_METADATA_DENY")
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+	return &stub.ForwardMetadata{Allow: allow, Deny: deny}
+}
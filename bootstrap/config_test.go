@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"os"
+	"testing"
+)
+
+// setEnv sets the environment variable, returning a function that restores its previous value
+func setEnv(name, value string) func() {
+	previous, found := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if found {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestConfigFromEnv_NotMatchedCode(t *testing.T) {
+	assert.Equal(t, codes.NotFound, configFromEnv().NotMatchedCode)
+
+	defer setEnv("NOT_MATCHED_CODE", "12")()
+	assert.Equal(t, codes.Unimplemented, configFromEnv().NotMatchedCode)
+}
+
+func TestWithNotMatchedCode(t *testing.T) {
+	config := configFromEnv()
+	WithNotMatchedCode(codes.FailedPrecondition)(&config)
+	assert.Equal(t, codes.FailedPrecondition, config.NotMatchedCode)
+}
+
+func TestConfigFromEnv_TLS(t *testing.T) {
+	assert.Equal(t, TLSConfig{}, configFromEnv().TLS)
+
+	defer setEnv("TLS_CERT_FILE", "cert.pem")()
+	defer setEnv("TLS_KEY_FILE", "key.pem")()
+	defer setEnv("TLS_CLIENT_CA_FILE", "ca.pem")()
+	defer setEnv("TLS_SELF_SIGNED", "true")()
+	assert.Equal(t, TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem", SelfSigned: true}, configFromEnv().TLS)
+}
+
+func TestWithTLS(t *testing.T) {
+	defer setEnv("TLS_SELF_SIGNED", "true")()
+	config := configFromEnv()
+	WithTLS(TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"})(&config)
+	assert.Equal(t, TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, config.TLS)
+}
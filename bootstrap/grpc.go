@@ -18,10 +18,10 @@ import (
 var server *grpc.Server
 var listener net.Listener
 
-// Start the server for the previously registered services
-func StarGRPCServer(port uint, service grpchandler.MockService) {
+// Start the server for the previously registered services. The options are passed to the gRPC server, like its credentials.
+func StarGRPCServer(port uint, service grpchandler.MockService, opts ...grpc.ServerOption) {
 
-	server = grpc.NewServer()
+	server = grpc.NewServer(opts...)
 	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
 	reflection.Register(server)
 
@@ -35,7 +35,7 @@ func StarGRPCServer(port uint, service grpchandler.MockService) {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 	log.Infof("gRPC Server listening on port: %d", port)
-	go serv(listener)
+	go serv(grpchandler.TrackConnections(listener)) // lets faults close the connection of a call
 
 	if err != nil {
 		log.Fatalf("GRPC server failed to start %+v", err)
@@ -60,6 +60,8 @@ func cleanup() {
 	server.GracefulStop()
 	log.Info("Closing the listener")
 	listener.Close()
+	log.Info("Closing the connections to the upstreams")
+	grpchandler.CloseForwardConnections()
 	log.Info("End of Program")
 }
 
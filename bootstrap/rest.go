@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
 	"github.com/carvalhorr/protoc-gen-mock/restcontrollers"
@@ -11,6 +12,11 @@ import (
 )
 
 func StartRESTServer(port uint, controllers []restcontrollers.RESTController) {
+	StartRESTServerWithTLS(port, controllers, nil)
+}
+
+// StartRESTServerWithTLS starts the REST server with TLS, or with plaintext when the TLS configuration is nil
+func StartRESTServerWithTLS(port uint, controllers []restcontrollers.RESTController, tlsConfig *tls.Config) {
 	log.Infof("REST Server listening on port: %d", port)
 
 	r := mux.NewRouter()
@@ -21,23 +27,58 @@ func StartRESTServer(port uint, controllers []restcontrollers.RESTController) {
 		}
 	}
 
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), r))
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", port),
+		Handler:   r,
+		TLSConfig: tlsConfig,
+	}
+	if tlsConfig != nil {
+		log.Fatal(server.ListenAndServeTLS("", "")) // the certificates are in the TLS configuration
+	}
+	log.Fatal(server.ListenAndServe())
 }
 
 func CreateRESTControllers(
 	stubExamples []stub.Stub,
 	stubsStore stub.StubsStore,
+	stubsMatcher stub.StubsMatcher,
+	scenariosStore stub.ScenariosStore,
 	recordingsStore stub.RecordingsStore,
+	requestJournal stub.RequestJournal,
+	faultsStore stub.FaultsStore,
+	upstreamsStore stub.UpstreamsStore,
 	service grpchandler.MockService) []restcontrollers.RESTController {
+	stubsController := restcontrollers.StubsController{
+		StubsStore:   stubsStore,
+		StubExamples: stubExamples,
+		Service:      service,
+	}
 	return []restcontrollers.RESTController{
 		restcontrollers.ExamplesController{StubExamples: stubExamples},
-		restcontrollers.StubsController{
-			StubsStore:   stubsStore,
-			StubExamples: stubExamples,
-			Service:      service,
-		},
+		stubsController,
 		restcontrollers.RecordingsController{
 			RecordingsStore: recordingsStore,
+			Stubs:           stubsController,
+		},
+		restcontrollers.ScenariosController{
+			StubsStore:     stubsStore,
+			ScenariosStore: scenariosStore,
+		},
+		restcontrollers.RequestsController{
+			RequestJournal: requestJournal,
+			Service:        service,
+		},
+		restcontrollers.DiagnosticsController{
+			StubsMatcher: stubsMatcher,
+			Service:      service,
+		},
+		restcontrollers.FaultsController{
+			FaultsStore: faultsStore,
+			Service:     service,
+		},
+		restcontrollers.UpstreamsController{
+			UpstreamsStore: upstreamsStore,
+			Service:        service,
 		},
 	}
 }
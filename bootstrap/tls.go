@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// selfSignedCertFile is the file in the temporary path the self-signed certificate is written to, so that clients can trust it
+const selfSignedCertFile = "self-signed-cert.pem"
+
+// TLSConfig is the TLS configuration of the gRPC and REST servers. They use plaintext when neither a certificate
+// nor a self-signed one are configured. Certificates and keys are read from PEM files.
+type TLSConfig struct {
+	CertFile     string // certificate of the servers. Requires KeyFile.
+	KeyFile      string // key of the certificate
+	ClientCAFile string // CA certificates the client certificates are verified with. Clients must present one when set (mTLS).
+	SelfSigned   bool   // generates a self-signed certificate for localhost at startup when no certificate is provided. Only meant for local use.
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.SelfSigned
+}
+
+// serverConfig builds the TLS configuration of the servers, or returns nil when they use plaintext
+func (c TLSConfig) serverConfig(tmpPath string) (*tls.Config, error) {
+	if !c.enabled() {
+		if c.ClientCAFile != "" {
+			return nil, fmt.Errorf("a client CA requires a certificate or a self-signed one")
+		}
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	var certificate tls.Certificate
+	var err error
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.SelfSigned {
+			log.Warn("A certificate is provided, so no self-signed certificate is generated")
+		}
+		certificate, err = tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	} else {
+		certificate, err = selfSignedCertificate(filepath.Join(tmpPath, selfSignedCertFile))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load the server certificate: %w", err)
+	}
+	config.Certificates = []tls.Certificate{certificate}
+	if c.ClientCAFile != "" {
+		ca, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the client CA file: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no PEM certificate found in the client CA file %s", c.ClientCAFile)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// selfSignedCertificate generates a certificate for localhost valid for a year and writes it to the file
+func selfSignedCertificate(certFile string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"protoc-gen-mock"}, CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		log.Warnf("Could not write the self-signed certificate to %s: %s", certFile, err)
+	} else {
+		log.Infof("Generated a self-signed certificate for localhost. Clients can trust it with %s", certFile)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
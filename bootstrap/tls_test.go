@@ -0,0 +1,97 @@
+package bootstrap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCertificate generates a self-signed certificate and writes it with its key to PEM files in the directory
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	certFile = filepath.Join(dir, "cert.pem")
+	certificate, err := selfSignedCertificate(certFile)
+	assert.Nil(t, err)
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	assert.Nil(t, err)
+	keyFile = filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+	return certFile, keyFile
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "tls")
+	assert.Nil(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestTLSConfig_serverConfig_Plaintext(t *testing.T) {
+	config, err := TLSConfig{}.serverConfig("")
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+
+	_, err = TLSConfig{ClientCAFile: "ca.pem"}.serverConfig("")
+	assert.EqualError(t, err, "a client CA requires a certificate or a self-signed one")
+}
+
+func TestTLSConfig_serverConfig_SelfSigned(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+
+	config, err := TLSConfig{SelfSigned: true}.serverConfig(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, 1, len(config.Certificates))
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+
+	// the certificate written is the one served, and it is valid for localhost
+	certPEM, err := ioutil.ReadFile(filepath.Join(dir, selfSignedCertFile))
+	assert.Nil(t, err)
+	block, _ := pem.Decode(certPEM)
+	assert.Equal(t, config.Certificates[0].Certificate[0], block.Bytes)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+		_, err = certificate.Verify(x509.VerifyOptions{DNSName: name, Roots: roots})
+		assert.Nil(t, err, name)
+	}
+}
+
+func TestTLSConfig_serverConfig_Certificate(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := TLSConfig{CertFile: certFile, KeyFile: keyFile, SelfSigned: true}.serverConfig(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(config.Certificates))
+	certPEM, _ := ioutil.ReadFile(certFile)
+	block, _ := pem.Decode(certPEM)
+	assert.Equal(t, block.Bytes, config.Certificates[0].Certificate[0])
+
+	_, err = TLSConfig{CertFile: certFile}.serverConfig(dir)
+	assert.Contains(t, err.Error(), "could not load the server certificate")
+}
+
+func TestTLSConfig_serverConfig_ClientCA(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}.serverConfig(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+	assert.NotNil(t, config.ClientCAs)
+
+	_, err = TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(dir, "missing.pem")}.serverConfig(dir)
+	assert.Contains(t, err.Error(), "could not read the client CA file")
+
+	_, err = TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}.serverConfig(dir)
+	assert.Contains(t, err.Error(), "no PEM certificate found in the client CA file")
+}
@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/carvalhorr/goutils v0.0.1
 	github.com/golang/protobuf v1.4.3
+	github.com/google/cel-go v0.7.3
 	github.com/gorilla/mux v1.8.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/stretchr/stew v0.0.0-20130812190256-80ef0842b48b
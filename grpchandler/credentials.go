@@ -0,0 +1,42 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const authorizationKey = "authorization"
+
+// forwardCredentials sends the credentials of a forward as the authorization metadata of the calls forwarded
+type forwardCredentials struct {
+	credentials *stub.ForwardCredentials
+}
+
+// callCredentials returns the call options sending the credentials of the forward, if any
+func callCredentials(forward *stub.StubForward) []grpc.CallOption {
+	if forward.Credentials == nil {
+		return nil
+	}
+	return []grpc.CallOption{grpc.PerRPCCredentials(forwardCredentials{credentials: forward.Credentials})}
+}
+
+// GetRequestMetadata is called with the context of the call forwarded, which carries the metadata of the call received
+func (c forwardCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if c.credentials.BearerToken != "" {
+		return map[string]string{authorizationKey: "Bearer " + c.credentials.BearerToken}, nil
+	}
+	if c.credentials.ForwardAuthorization {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			return map[string]string{authorizationKey: values[0]}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RequireTransportSecurity allows sending the credentials to plaintext upstreams too, like local test servers
+func (c forwardCredentials) RequireTransportSecurity() bool {
+	return false
+}
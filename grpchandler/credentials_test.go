@@ -0,0 +1,28 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestForwardCredentials_BearerToken(t *testing.T) {
+	creds := forwardCredentials{credentials: &stub.ForwardCredentials{BearerToken: "token"}}
+	md, err := creds.GetRequestMetadata(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token"}, md)
+}
+
+func TestForwardCredentials_ForwardAuthorization(t *testing.T) {
+	creds := forwardCredentials{credentials: &stub.ForwardCredentials{ForwardAuthorization: true}}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic abc"))
+	md, err := creds.GetRequestMetadata(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Basic abc"}, md)
+
+	md, err = creds.GetRequestMetadata(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, md)
+}
@@ -0,0 +1,103 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync"
+)
+
+var faultsStore stub.FaultsStore
+
+// SetFaultsStore sets the store of the faults applied to all the calls to a method
+func SetFaultsStore(store stub.FaultsStore) {
+	faultsStore = store
+}
+
+// methodFault returns the fault set for all the calls to the method, if any
+func methodFault(fullMethod string) *stub.Fault {
+	if faultsStore == nil {
+		return nil
+	}
+	return faultsStore.Get(fullMethod)
+}
+
+// injectFault samples the fault for the call and returns the error to end the call with, if any
+func injectFault(ctx context.Context, fullMethod string, fault *stub.Fault) error {
+	if fault == nil {
+		return nil
+	}
+	injected := fault.Inject()
+	if err := wait(ctx, injected.Delay); err != nil {
+		return err
+	}
+	if injected.Abort == "connection" {
+		log.Infof("Fault injection: closing the connection of the call to %s", fullMethod)
+		if !closeConnection(ctx) {
+			log.Warnf("Fault injection: the connection of the call to %s is not tracked, failing the call with UNAVAILABLE instead", fullMethod)
+		}
+		return status.Error(codes.Unavailable, "connection closed by fault injection")
+	}
+	if injected.Error != nil {
+		log.Infof("Fault injection: failing the call to %s with %s", fullMethod, injected.Error)
+	}
+	return injected.Error
+}
+
+// connections are the connections accepted by the listeners wrapped by TrackConnections, by remote address
+var connections = make(map[string]net.Conn)
+var connectionsMutex sync.Mutex
+
+// TrackConnections wraps the listener of the gRPC server so that faults can close the connection of a call
+func TrackConnections(listener net.Listener) net.Listener {
+	return trackingListener{Listener: listener}
+}
+
+type trackingListener struct {
+	net.Listener
+}
+
+func (l trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+	connections[conn.RemoteAddr().String()] = conn
+	return &trackedConn{Conn: conn}, nil
+}
+
+type trackedConn struct {
+	net.Conn
+}
+
+func (c *trackedConn) Close() error {
+	connectionsMutex.Lock()
+	if connections[c.RemoteAddr().String()] == c.Conn {
+		delete(connections, c.RemoteAddr().String())
+	}
+	connectionsMutex.Unlock()
+	return c.Conn.Close()
+}
+
+// closeConnection closes the connection the call was made on. It returns false when the connection isn't tracked.
+func closeConnection(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return false
+	}
+	connectionsMutex.Lock()
+	conn, ok := connections[p.Addr.String()]
+	delete(connections, p.Addr.String())
+	connectionsMutex.Unlock()
+	if !ok {
+		return false
+	}
+	conn.Close()
+	return true
+}
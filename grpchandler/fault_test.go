@@ -0,0 +1,84 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestInjectFault_None(t *testing.T) {
+	assert.Nil(t, injectFault(context.Background(), "/pkg.Service/Method", nil))
+	assert.Nil(t, injectFault(context.Background(), "/pkg.Service/Method", &stub.Fault{Error: &stub.ErrorFault{Percentage: 0, Code: 14}}))
+}
+
+func TestInjectFault_Error(t *testing.T) {
+	fault := &stub.Fault{Error: &stub.ErrorFault{Percentage: 100, Code: uint32(codes.ResourceExhausted), Message: "quota"}}
+
+	err := injectFault(context.Background(), "/pkg.Service/Method", fault)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "quota", status.Convert(err).Message())
+}
+
+func TestInjectFault_Delay(t *testing.T) {
+	fault := &stub.Fault{Delay: &stub.DelayFault{Percentage: 100, Delay: &stub.ResponseDelay{Type: "fixed", Fixed: 50}}}
+
+	start := time.Now()
+	assert.Nil(t, injectFault(context.Background(), "/pkg.Service/Method", fault))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(injectFault(ctx, "/pkg.Service/Method", fault)))
+}
+
+func TestInjectFault_AbortUntrackedConnection(t *testing.T) {
+	fault := &stub.Fault{Abort: &stub.AbortFault{Percentage: 100, Type: "connection"}}
+
+	err := injectFault(context.Background(), "/pkg.Service/Method", fault)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestInjectFault_AbortConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	tracking := TrackConnections(listener)
+	defer tracking.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer client.Close()
+	server, err := tracking.Accept()
+	assert.Nil(t, err)
+	defer server.Close()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: server.RemoteAddr()})
+	fault := &stub.Fault{Abort: &stub.AbortFault{Percentage: 100, Type: "connection"}}
+
+	err = injectFault(ctx, "/pkg.Service/Method", fault)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = client.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.False(t, closeConnection(ctx))
+}
+
+func TestMethodFault(t *testing.T) {
+	defer SetFaultsStore(nil)
+	assert.Nil(t, methodFault("/pkg.Service/Method"))
+
+	store := stub.NewInMemoryFaultsStore()
+	fault := &stub.Fault{Error: &stub.ErrorFault{Percentage: 100, Code: 14}}
+	store.Set("/pkg.Service/Method", fault)
+	SetFaultsStore(store)
+
+	assert.Equal(t, fault, methodFault("/pkg.Service/Method"))
+	assert.Nil(t, methodFault("/pkg.Service/Other"))
+}
@@ -10,6 +10,9 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"reflect"
+	"time"
 )
 
 var supportedMockService MockService
@@ -28,30 +31,41 @@ func forwardAndRecord(s *stub.Stub, ctx context.Context, fullMethod string, req,
 		return nil, status.Error(codes.Internal, "Attempt to cal forward for a stub that is not of type 'forward'")
 	}
 	log.Infof("Forwarding to %s (%s -> %s)", s.Forward.ServerAddress, fullMethod, s.Request.String())
-	conn := createConnection(s.Forward)
-	defer conn.Close()
+	conn, release, err := forwardConnections.get(s.Forward)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	forwardCtx, cancel := outgoingContext(ctx, s.Forward)
+	defer cancel()
 
-	resp, err = supportedMockService.ForwardRequest(conn, ctx, fullMethod, req)
+	var header, trailer metadata.MD
+	opts := append(callCredentials(s.Forward), grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err = supportedMockService.ForwardRequest(conn, forwardCtx, fullMethod, req, opts...)
 	log.Infof("Got forward response %s and error %s", toProtoJson(resp), errToString(err))
+	delete(header, "content-type") // set by gRPC on every response
+	upstream := &stub.StubResponse{Headers: header, Trailers: trailer}
+	stub.SetResponseMetadata(ctx, upstream)
 	if s.Forward.Record {
 		log.Infof("Recording is active for stub %s -> %s", fullMethod, s.Request.String())
-		recordRequestAndResponse(ctx, fullMethod, req, resp, err)
+		recordRequestAndResponse(ctx, fullMethod, req, resp, err, upstream)
 	}
 	return resp, err
 }
 
-func createConnection(forward *stub.StubForward) *grpc.ClientConn {
-
-	options := make([]grpc.DialOption, 0)
-	options = append(options, grpc.WithInsecure()) // TODO deal with security
-	conn, err := grpc.Dial(forward.ServerAddress, options...)
-	if err != nil {
-		log.Errorf("Failed to create connection to %s", forward.ServerAddress)
+// outgoingContext returns the context the call is forwarded with: it carries the metadata propagated to the server
+// and the deadline of the call, shortened by the timeout of the forward if it ends earlier
+func outgoingContext(ctx context.Context, forward *stub.StubForward) (context.Context, context.CancelFunc) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	outgoingCtx := metadata.NewOutgoingContext(ctx, forward.OutgoingMetadata(md))
+	if forward.Timeout == 0 {
+		return context.WithCancel(outgoingCtx)
 	}
-	return conn
+	return context.WithTimeout(outgoingCtx, time.Duration(forward.Timeout)*time.Millisecond)
 }
 
-func recordRequestAndResponse(ctx context.Context, fullMethod string, req, resp interface{}, err error) {
+// recordRequestAndResponse records the forwarded call as a stub, with the headers and trailers of the upstream response
+func recordRequestAndResponse(ctx context.Context, fullMethod string, req, resp interface{}, err error, upstream *stub.StubResponse) {
 	s := &stub.Stub{
 		FullMethod: fullMethod,
 		Type:       "mock",
@@ -61,9 +75,11 @@ func recordRequestAndResponse(ctx context.Context, fullMethod string, req, resp
 			Metadata: getMetadata(ctx),
 		},
 		Response: &stub.StubResponse{
-			Type:    getResponseType(resp, err),
-			Content: toProtoJson(resp),
-			Error:   mapError(err),
+			Type:     getResponseType(resp, err),
+			Content:  toProtoJson(resp),
+			Error:    mapError(err),
+			Headers:  upstream.Headers,
+			Trailers: upstream.Trailers,
 		},
 		Forward: nil,
 	}
@@ -103,7 +119,48 @@ func errToString(err error) string {
 	return err.Error()
 }
 
+// mapError converts the error of a forwarded call into the error response of its recording. The details of the status
+// are recorded with the Go type of their message so that the custom error engine can recreate them on replay.
 func mapError(err error) *stub.ErrorResponse {
-	// TODO Map error response when forwarding for recording
-	return nil
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	return &stub.ErrorResponse{
+		Code:    uint32(st.Code()),
+		Message: st.Message(),
+		Details: mapErrorDetails(st.Proto().GetDetails()),
+	}
+}
+
+func mapErrorDetails(details []*anypb.Any) *stub.ErrorDetails {
+	var errorDetails *stub.ErrorDetails
+	for _, detail := range details {
+		message, err := detail.UnmarshalNew()
+		if err != nil {
+			log.Warnf("Error detail of type %s can't be recorded: %s", detail.GetTypeUrl(), err)
+			continue
+		}
+		spec := getErrorDetailsSpec(message)
+		value := stub.ErrorDetailsValue{Value: toProtoJson(message)}
+		if errorDetails == nil {
+			errorDetails = &stub.ErrorDetails{Spec: spec}
+		} else if *spec != *errorDetails.Spec {
+			value.SpecOverride = spec
+		}
+		errorDetails.Values = append(errorDetails.Values, value)
+	}
+	return errorDetails
+}
+
+// getErrorDetailsSpec returns the Go package and type of the generated message
+func getErrorDetailsSpec(message proto.Message) *stub.ErrorDetailsSpec {
+	messageType := reflect.TypeOf(message)
+	if messageType.Kind() == reflect.Ptr {
+		messageType = messageType.Elem()
+	}
+	return &stub.ErrorDetailsSpec{
+		Import: messageType.PkgPath(),
+		Type:   messageType.Name(),
+	}
 }
@@ -0,0 +1,79 @@
+package grpchandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"testing"
+	"time"
+)
+
+func TestMapError_Nil(t *testing.T) {
+	assert.Nil(t, mapError(nil))
+}
+
+func TestMapError_NotStatus(t *testing.T) {
+	assert.Equal(t, &stub.ErrorResponse{Code: uint32(codes.Unknown), Message: "failed"}, mapError(errors.New("failed")))
+}
+
+func TestMapError_WithDetails(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, "precondition failed").
+		WithDetails(wrapperspb.String("first"), durationpb.New(1000000000), wrapperspb.String("second"))
+	assert.Nil(t, err)
+
+	stringSpec := &stub.ErrorDetailsSpec{Import: "google.golang.org/protobuf/types/known/wrapperspb", Type: "StringValue"}
+	durationSpec := &stub.ErrorDetailsSpec{Import: "google.golang.org/protobuf/types/known/durationpb", Type: "Duration"}
+	assert.Equal(t, &stub.ErrorResponse{
+		Code:    uint32(codes.FailedPrecondition),
+		Message: "precondition failed",
+		Details: &stub.ErrorDetails{
+			Spec: stringSpec,
+			Values: []stub.ErrorDetailsValue{
+				{Value: `"first"`},
+				{SpecOverride: durationSpec, Value: `"1s"`},
+				{Value: `"second"`},
+			},
+		},
+	}, mapError(st.Err()))
+}
+
+func TestMapError_WithDetails_RoundTrip(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, "precondition failed").WithDetails(durationpb.New(1500 * time.Millisecond))
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(mapError(st.Err()))
+	assert.Nil(t, err)
+	loaded := new(stub.ErrorResponse)
+	assert.Nil(t, json.Unmarshal(data, loaded))
+
+	duration := new(durationpb.Duration)
+	assert.Nil(t, protojson.Unmarshal([]byte(loaded.Details.Values[0].Value), duration))
+	assert.Equal(t, 1500*time.Millisecond, duration.AsDuration())
+}
+
+func TestOutgoingContext(t *testing.T) {
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "t1", "user-agent", "client"))
+	callerCtx, cancel := context.WithTimeout(incoming, time.Second)
+	defer cancel()
+
+	ctx, cancelForward := outgoingContext(callerCtx, &stub.StubForward{Timeout: 5000})
+	defer cancelForward()
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Equal(t, metadata.Pairs("x-tenant", "t1"), md)
+	callerDeadline, _ := callerCtx.Deadline()
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, callerDeadline, deadline)
+
+	ctx, cancelForward = outgoingContext(callerCtx, &stub.StubForward{Timeout: 10})
+	defer cancelForward()
+	deadline, _ = ctx.Deadline()
+	assert.True(t, deadline.Before(callerDeadline))
+}
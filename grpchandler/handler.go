@@ -5,26 +5,74 @@ import (
 	"fmt"
 	"github.com/carvalhorr/protoc-gen-mock/stub"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"sync/atomic"
 )
 
+// notMatchedCode is the status code returned when no stub matches a call. It is read and set atomically.
+var notMatchedCode = uint32(codes.NotFound)
+
+// SetNotMatchedCode sets the status code returned when no stub matches a call
+func SetNotMatchedCode(code codes.Code) {
+	atomic.StoreUint32(&notMatchedCode, uint32(code))
+}
+
+// getNotMatchedCode returns the status code returned when no stub matches a call
+func getNotMatchedCode() codes.Code {
+	return codes.Code(atomic.LoadUint32(&notMatchedCode))
+}
+
 // MockInterceptor intercepts the gRPC calls for the registered services return canned responses previously loaded through the REST API.
-var MockHandler = func(ctx context.Context, stubsMatcher stub.StubsMatcher, fullMethod string, req interface{}, resp interface{}) (_ interface{}, err error) {
+var MockHandler = func(ctx context.Context, stubsMatcher stub.StubsMatcher, fullMethod string, req interface{}, resp interface{}) (response interface{}, err error) {
+	call := newJournalCall(ctx, fullMethod, false, false)
+	defer func() {
+		if err == nil {
+			call.addResponse(response)
+		}
+		call.record(err)
+	}()
 	paramsJson, err := getRequestInJSON(req)
 	if err != nil {
 		logError(fullMethod, paramsJson, err)
 		return nil, err
 	}
+	call.addRequest(paramsJson)
+	if err := injectFault(ctx, fullMethod, methodFault(fullMethod)); err != nil {
+		return nil, err
+	}
 	s := stubsMatcher.Match(ctx, fullMethod, paramsJson)
+	call.stub = s
 	if s == nil {
+		if upstream := upstreamStub(fullMethod, paramsJson); upstream != nil {
+			log.Infof("NO mock response found for %s --> %s. Forwarding to the upstream.", fullMethod, paramsJson)
+			call.stub = upstream
+			return forwardAndRecord(upstream, ctx, fullMethod, req, resp)
+		}
 		log.Infof("NO mock response found for %s --> %s", fullMethod, paramsJson)
-		return nil, fmt.Errorf("no response found")
+		return nil, notMatchedError(fullMethod, stubsMatcher.NearMisses(ctx, fullMethod, paramsJson))
+	}
+	if err := injectFault(ctx, fullMethod, s.Fault); err != nil {
+		return nil, err
 	}
 	if s.Type == "forward" {
 		return forwardAndRecord(s, ctx, fullMethod, req, resp)
 	}
-	return stub.GetResponse(s, paramsJson, resp)
+	response, delay, err := stub.GetDelayedResponse(ctx, s, paramsJson, resp)
+	if waitErr := wait(ctx, delay); waitErr != nil {
+		log.Infof("Call to %s ended while delaying the response: %s", fullMethod, waitErr)
+		return nil, waitErr
+	}
+	return response, err
+}
+
+// notMatchedError reports the stubs closest to matching the call and how they differ from it
+func notMatchedError(fullMethod string, nearMisses []stub.NearMiss) error {
+	report := stub.NearMissesReport(nearMisses)
+	log.Infof("Near misses for %s --> %s", fullMethod, report)
+	return status.Errorf(getNotMatchedCode(), "no response found for %s\n%s", fullMethod, report)
 }
 
 func logError(fullMethod, paramsJSON string, err error) {
@@ -0,0 +1,96 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+var requestJournal stub.RequestJournal
+
+// SetRequestJournal sets the journal where every call received by the mock handlers is recorded, matched or not
+func SetRequestJournal(journal stub.RequestJournal) {
+	requestJournal = journal
+}
+
+// journalCall collects what happened during a call until it is recorded in the journal
+type journalCall struct {
+	ctx             context.Context
+	received        time.Time
+	fullMethod      string
+	clientStreaming bool
+	serverStreaming bool
+	requests        []string
+	responses       []string
+	stub            *stub.Stub
+}
+
+func newJournalCall(ctx context.Context, fullMethod string, clientStreaming, serverStreaming bool) *journalCall {
+	return &journalCall{
+		ctx:             ctx,
+		received:        time.Now(),
+		fullMethod:      fullMethod,
+		clientStreaming: clientStreaming,
+		serverStreaming: serverStreaming,
+	}
+}
+
+func (c *journalCall) addRequest(requestJson string) {
+	c.requests = append(c.requests, requestJson)
+}
+
+func (c *journalCall) addResponse(response interface{}) {
+	if response == nil {
+		return
+	}
+	c.responses = append(c.responses, string(toProtoJson(response)))
+}
+
+func (c *journalCall) record(err error) {
+	if requestJournal == nil {
+		return
+	}
+	requestJournal.Add(stub.JournalEntry{
+		FullMethod: c.fullMethod,
+		Request:    stub.JsonString(journalJson(c.requests, c.clientStreaming)),
+		Metadata:   getMetadata(c.ctx),
+		Peer:       getPeerAddress(c.ctx),
+		Timestamp:  c.received,
+		Stub:       c.stub,
+		Response:   stub.JsonString(journalJson(c.responses, c.serverStreaming)),
+		Status:     toJournalStatus(err),
+	})
+}
+
+// journalJson returns the single message of the side of a call that isn't streaming, or all the messages as a JSON array otherwise
+func journalJson(messages []string, streaming bool) string {
+	if streaming {
+		return "[" + strings.Join(messages, ",") + "]"
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[0]
+}
+
+func toJournalStatus(err error) *stub.ErrorResponse {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	return &stub.ErrorResponse{
+		Code:    uint32(st.Code()),
+		Message: st.Message(),
+	}
+}
+
+func getPeerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
@@ -0,0 +1,73 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net"
+	"testing"
+)
+
+func TestJournalCall_Record(t *testing.T) {
+	journal := stub.NewInMemoryRequestJournal(10)
+	SetRequestJournal(journal)
+	defer SetRequestJournal(nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("tenant", "a"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}})
+	s := &stub.Stub{FullMethod: "/pkg.Service/Method"}
+
+	call := newJournalCall(ctx, "/pkg.Service/Method", false, false)
+	call.addRequest(`{"name":"a"}`)
+	call.addResponse(wrapperspb.String("hello"))
+	call.addResponse(nil)
+	call.stub = s
+	call.record(nil)
+
+	entries := journal.Find(stub.JournalFilter{})
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "/pkg.Service/Method", entries[0].FullMethod)
+	assert.Equal(t, stub.JsonString(`{"name":"a"}`), entries[0].Request)
+	assert.Equal(t, stub.JsonString(`"hello"`), entries[0].Response)
+	assert.Equal(t, map[string][]string{"tenant": {"a"}}, entries[0].Metadata)
+	assert.Equal(t, "127.0.0.1:5000", entries[0].Peer)
+	assert.Same(t, s, entries[0].Stub)
+	assert.Nil(t, entries[0].Status)
+	assert.False(t, entries[0].Timestamp.IsZero())
+}
+
+func TestJournalCall_RecordStreaming(t *testing.T) {
+	journal := stub.NewInMemoryRequestJournal(10)
+	SetRequestJournal(journal)
+	defer SetRequestJournal(nil)
+
+	call := newJournalCall(context.Background(), "/pkg.Service/Stream", true, true)
+	call.addRequest(`{"n":1}`)
+	call.addRequest(`{"n":2}`)
+	call.record(status.Error(codes.NotFound, "no stub"))
+
+	entries := journal.Find(stub.JournalFilter{})
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, stub.JsonString(`[{"n":1},{"n":2}]`), entries[0].Request)
+	assert.Equal(t, stub.JsonString(`[]`), entries[0].Response)
+	assert.Equal(t, &stub.ErrorResponse{Code: uint32(codes.NotFound), Message: "no stub"}, entries[0].Status)
+	assert.Equal(t, "", entries[0].Peer)
+	assert.Nil(t, entries[0].Metadata)
+}
+
+func TestJournalCall_RecordWithoutJournal(t *testing.T) {
+	SetRequestJournal(nil)
+	call := newJournalCall(context.Background(), "/pkg.Service/Method", false, false)
+
+	assert.NotPanics(t, func() { call.record(nil) })
+}
+
+func TestToJournalStatus(t *testing.T) {
+	assert.Nil(t, toJournalStatus(nil))
+	assert.Equal(t, &stub.ErrorResponse{Code: uint32(codes.Unknown), Message: "failed"}, toJournalStatus(errors.New("failed")))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/carvalhorr/protoc-gen-mock/stub"
 	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/stew/slice"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -17,10 +18,38 @@ type MockService interface {
 	GetPayloadExamples() []stub.Stub
 	GetRequestInstance(methodName string) proto.Message
 	GetResponseInstance(methodName string) proto.Message
-	ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}) (interface{}, error)
+	ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}, opts ...grpc.CallOption) (interface{}, error)
 	GetStubsValidator() stub.StubsValidator
 }
 
+// MethodAliasesProvider is implemented by the mock services that accept deprecated full method names, built from the
+// Go name of the methods. It isn't part of MockService so that services generated by previous versions keep compiling.
+type MethodAliasesProvider interface {
+	// GetMethodAliases maps deprecated full method names to the full method names gRPC routes on
+	GetMethodAliases() map[string]string
+}
+
+// GetMethodAliases returns the method aliases of the service, or none if it doesn't provide them
+func GetMethodAliases(service MockService) map[string]string {
+	if provider, ok := service.(MethodAliasesProvider); ok {
+		return provider.GetMethodAliases()
+	}
+	return map[string]string{}
+}
+
+// ResolveMethod translates a deprecated full method name to the name gRPC routes on.
+// Stubs and calls to the REST API written for previous versions keep working this way.
+func ResolveMethod(service MockService, method string) string {
+	if method == "" {
+		return method
+	}
+	if resolved, ok := GetMethodAliases(service)[method]; ok {
+		log.Warnf("Method name %s is deprecated. Use %s instead.", method, resolved)
+		return resolved
+	}
+	return method
+}
+
 func NewCompositeMockService(services []MockService) MockService {
 	return compositeMockService{
 		mockServices: services,
@@ -45,6 +74,16 @@ func (c compositeMockService) GetSupportedMethods() []string {
 	return methods
 }
 
+func (c compositeMockService) GetMethodAliases() map[string]string {
+	aliases := make(map[string]string, 0)
+	for _, mockService := range c.mockServices {
+		for alias, method := range GetMethodAliases(mockService) {
+			aliases[alias] = method
+		}
+	}
+	return aliases
+}
+
 func (c compositeMockService) GetPayloadExamples() []stub.Stub {
 	examples := make([]stub.Stub, 0)
 	for _, mockService := range c.mockServices {
@@ -81,10 +120,10 @@ func (c compositeMockService) GetStubsValidator() stub.StubsValidator {
 	return stub.NewCompositeStubsValidator(validators)
 }
 
-func (c compositeMockService) ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}) (interface{}, error) {
+func (c compositeMockService) ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}, opts ...grpc.CallOption) (interface{}, error) {
 	for _, service := range c.mockServices {
 		if slice.Contains(service.GetSupportedMethods(), methodName) {
-			return service.ForwardRequest(conn, ctx, methodName, req)
+			return service.ForwardRequest(conn, ctx, methodName, req, opts...)
 		}
 	}
 	return nil, status.Error(codes.NotFound, fmt.Sprintf("Method %s is not supported.", methodName))
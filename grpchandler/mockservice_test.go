@@ -0,0 +1,47 @@
+package grpchandler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// aliasedMockService is a mock service accepting the deprecated names of its methods
+type aliasedMockService struct {
+	MockService
+	aliases map[string]string
+}
+
+func (s aliasedMockService) GetMethodAliases() map[string]string {
+	return s.aliases
+}
+
+func TestGetMethodAliases(t *testing.T) {
+	service := aliasedMockService{aliases: map[string]string{"/test.Greeter/SayHello": "/test.Greeter/say_hello"}}
+
+	assert.Equal(t, service.aliases, GetMethodAliases(service))
+	assert.Equal(t, map[string]string{}, GetMethodAliases(nil))
+	assert.Equal(t, map[string]string{}, GetMethodAliases(compositeMockService{}))
+}
+
+func TestGetMethodAliases_Composite(t *testing.T) {
+	service := NewCompositeMockService([]MockService{
+		aliasedMockService{aliases: map[string]string{"/test.Greeter/SayHello": "/test.Greeter/say_hello"}},
+		aliasedMockService{aliases: map[string]string{"/test.Other/GetAll": "/test.Other/get_all"}},
+		compositeMockService{},
+	})
+
+	assert.Equal(t, map[string]string{
+		"/test.Greeter/SayHello": "/test.Greeter/say_hello",
+		"/test.Other/GetAll":     "/test.Other/get_all",
+	}, GetMethodAliases(service))
+}
+
+func TestResolveMethod(t *testing.T) {
+	service := aliasedMockService{aliases: map[string]string{"/test.Greeter/SayHello": "/test.Greeter/say_hello"}}
+
+	assert.Equal(t, "/test.Greeter/say_hello", ResolveMethod(service, "/test.Greeter/SayHello"))
+	assert.Equal(t, "/test.Greeter/say_hello", ResolveMethod(service, "/test.Greeter/say_hello"))
+	assert.Equal(t, "/test.Greeter/Other", ResolveMethod(service, "/test.Greeter/Other"))
+	assert.Equal(t, "", ResolveMethod(service, ""))
+	assert.Equal(t, "/test.Greeter/SayHello", ResolveMethod(nil, "/test.Greeter/SayHello"))
+}
@@ -0,0 +1,173 @@
+package grpchandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// defaultIdleTimeout is how long a connection to an upstream is kept open without being used
+const defaultIdleTimeout = 5 * time.Minute
+
+// forwardConnections are the connections the calls are forwarded on, reused by all the calls to the same upstream
+var forwardConnections = newConnectionPool(defaultIdleTimeout)
+
+// SetForwardIdleTimeout sets how long a connection to an upstream is kept open without being used. Defaults to 5 minutes.
+func SetForwardIdleTimeout(idleTimeout time.Duration) {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+	forwardConnections.setIdleTimeout(idleTimeout)
+}
+
+// CloseForwardConnections closes all the connections to the upstreams. New ones are opened by the next forwarded calls.
+func CloseForwardConnections() {
+	forwardConnections.closeAll()
+}
+
+type connectionPool struct {
+	connections map[string]*pooledConnection
+	idleTimeout time.Duration
+	evicting    bool
+	mutex       sync.Mutex
+}
+
+type pooledConnection struct {
+	conn     *grpc.ClientConn
+	inUse    int
+	lastUsed time.Time
+}
+
+func newConnectionPool(idleTimeout time.Duration) *connectionPool {
+	return &connectionPool{
+		connections: make(map[string]*pooledConnection, 0),
+		idleTimeout: idleTimeout,
+	}
+}
+
+// get returns the connection to the upstream, opening it if needed. The release function must be called once the call ends.
+func (p *connectionPool) get(forward *stub.StubForward) (conn *grpc.ClientConn, release func(), err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := connectionKey(forward)
+	pooled, ok := p.connections[key]
+	if !ok || pooled.conn.GetState() == connectivity.Shutdown {
+		conn, err := dial(forward)
+		if err != nil {
+			return nil, nil, err
+		}
+		pooled = &pooledConnection{conn: conn}
+		p.connections[key] = pooled
+		p.startEviction()
+	}
+	pooled.inUse++
+	pooled.lastUsed = time.Now()
+	return pooled.conn, func() { p.release(pooled) }, nil
+}
+
+func (p *connectionPool) release(pooled *pooledConnection) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	pooled.inUse--
+	pooled.lastUsed = time.Now()
+}
+
+// connectionKey identifies the connections that can be shared: the ones to the same address with the same TLS configuration
+func connectionKey(forward *stub.StubForward) string {
+	if forward.TLS == nil {
+		return forward.ServerAddress
+	}
+	tlsConfig, _ := json.Marshal(forward.TLS)
+	return fmt.Sprintf("%s %s", forward.ServerAddress, tlsConfig)
+}
+
+func dial(forward *stub.StubForward) (*grpc.ClientConn, error) {
+	options := make([]grpc.DialOption, 0)
+	if forward.TLS == nil {
+		options = append(options, grpc.WithInsecure())
+	} else {
+		tlsConfig, err := forward.TLS.Config()
+		if err != nil {
+			log.Errorf("Failed to configure TLS for %s: %s", forward.ServerAddress, err)
+			return nil, status.Errorf(codes.Unavailable, "could not connect to %s: %s", forward.ServerAddress, err)
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	conn, err := grpc.Dial(forward.ServerAddress, options...)
+	if err != nil {
+		log.Errorf("Failed to create connection to %s: %s", forward.ServerAddress, err)
+		return nil, status.Errorf(codes.Unavailable, "could not connect to %s: %s", forward.ServerAddress, err)
+	}
+	log.Debugf("Opened connection to %s", forward.ServerAddress)
+	return conn, nil
+}
+
+// startEviction starts closing the connections that aren't used, unless it is already doing it
+func (p *connectionPool) startEviction() {
+	if p.evicting {
+		return
+	}
+	p.evicting = true
+	go func() {
+		for p.waitToEvict() {
+			p.evictIdle(time.Now())
+		}
+	}()
+}
+
+// waitToEvict waits for the next eviction. It returns false, ending the evictions, once the pool is empty.
+func (p *connectionPool) waitToEvict() bool {
+	p.mutex.Lock()
+	idleTimeout := p.idleTimeout
+	p.mutex.Unlock()
+	time.Sleep(idleTimeout / 2)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.connections) == 0 {
+		p.evicting = false
+		return false
+	}
+	return true
+}
+
+// evictIdle closes the connections not used since longer than the idle timeout
+func (p *connectionPool) evictIdle(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for key, pooled := range p.connections {
+		if pooled.inUse == 0 && now.Sub(pooled.lastUsed) >= p.idleTimeout {
+			log.Debugf("Closing idle connection to %s", key)
+			pooled.conn.Close()
+			delete(p.connections, key)
+		}
+	}
+}
+
+func (p *connectionPool) setIdleTimeout(idleTimeout time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.idleTimeout = idleTimeout
+}
+
+func (p *connectionPool) closeAll() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for key, pooled := range p.connections {
+		pooled.conn.Close()
+		delete(p.connections, key)
+	}
+}
@@ -0,0 +1,58 @@
+package grpchandler
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/connectivity"
+	"testing"
+	"time"
+)
+
+func TestConnectionPool_Reuse(t *testing.T) {
+	pool := newConnectionPool(time.Minute)
+	defer pool.closeAll()
+	forward := &stub.StubForward{ServerAddress: "localhost:1"}
+
+	conn1, release1, err := pool.get(forward)
+	assert.Nil(t, err)
+	conn2, release2, err := pool.get(forward)
+	assert.Nil(t, err)
+	assert.Same(t, conn1, conn2)
+	release1()
+	release2()
+
+	other, release, err := pool.get(&stub.StubForward{ServerAddress: "localhost:2"})
+	assert.Nil(t, err)
+	assert.NotSame(t, conn1, other)
+	release()
+}
+
+func TestConnectionPool_EvictIdle(t *testing.T) {
+	pool := newConnectionPool(time.Minute)
+	defer pool.closeAll()
+	idle, release, _ := pool.get(&stub.StubForward{ServerAddress: "localhost:1"})
+	release()
+	inUse, _, _ := pool.get(&stub.StubForward{ServerAddress: "localhost:2"})
+
+	pool.evictIdle(time.Now())
+	assert.Equal(t, 2, len(pool.connections))
+
+	pool.evictIdle(time.Now().Add(time.Minute))
+	assert.Equal(t, 1, len(pool.connections))
+	assert.Equal(t, connectivity.Shutdown, idle.GetState())
+	assert.NotEqual(t, connectivity.Shutdown, inUse.GetState())
+}
+
+func TestConnectionPool_ReplaceClosed(t *testing.T) {
+	pool := newConnectionPool(time.Minute)
+	defer pool.closeAll()
+	forward := &stub.StubForward{ServerAddress: "localhost:1"}
+	conn1, release, _ := pool.get(forward)
+	release()
+	conn1.Close()
+
+	conn2, release, err := pool.get(forward)
+	assert.Nil(t, err)
+	assert.NotSame(t, conn1, conn2)
+	release()
+}
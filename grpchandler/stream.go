@@ -0,0 +1,229 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MockServerStreamHandler receives the single request of a server streaming call and sends the messages of the matching stub on the stream.
+// Streaming calls can't be forwarded: a 'forward' stub matching the call ends it with the UNIMPLEMENTED status.
+var MockServerStreamHandler = func(stream grpc.ServerStream, stubsMatcher stub.StubsMatcher, fullMethod string, req interface{}, newResponse func() interface{}) (err error) {
+	ctx := stream.Context()
+	call := newJournalCall(ctx, fullMethod, false, true)
+	defer func() { call.record(err) }()
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	paramsJson, err := getRequestInJSON(req)
+	if err != nil {
+		logError(fullMethod, paramsJson, err)
+		return err
+	}
+	call.addRequest(paramsJson)
+	if err := injectFault(ctx, fullMethod, methodFault(fullMethod)); err != nil {
+		return err
+	}
+	s := stubsMatcher.Match(ctx, fullMethod, paramsJson)
+	call.stub = s
+	if s == nil {
+		log.Infof("NO mock response found for %s --> %s", fullMethod, paramsJson)
+		return notMatchedError(fullMethod, stubsMatcher.NearMisses(ctx, fullMethod, paramsJson))
+	}
+	if err := injectFault(ctx, fullMethod, s.Fault); err != nil {
+		return err
+	}
+	if s.Type == "forward" {
+		return forwardingNotSupported(fullMethod)
+	}
+	messages, delay, closeErr := stub.GetStreamResponse(ctx, s, paramsJson, newResponse)
+	if err := wait(ctx, delay); err != nil {
+		return err
+	}
+	for _, message := range messages {
+		if err := wait(ctx, message.Delay); err != nil {
+			return err
+		}
+		if err := stream.SendMsg(message.Message); err != nil {
+			return err
+		}
+		call.addResponse(message.Message)
+	}
+	return closeErr
+}
+
+// MockClientStreamHandler receives all the messages of a client streaming call and replies with the response of the stub matching them.
+// Like server streaming calls, it ends the call with the UNIMPLEMENTED status when the stub matching it is a 'forward' stub.
+var MockClientStreamHandler = func(stream grpc.ServerStream, stubsMatcher stub.StubsMatcher, fullMethod string, newRequest func() interface{}, resp interface{}) (err error) {
+	ctx := stream.Context()
+	call := newJournalCall(ctx, fullMethod, true, false)
+	defer func() { call.record(err) }()
+	requestsJson := make([]string, 0)
+	for {
+		req := newRequest()
+		err := stream.RecvMsg(req)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		paramsJson, err := getRequestInJSON(req)
+		if err != nil {
+			logError(fullMethod, paramsJson, err)
+			return err
+		}
+		requestsJson = append(requestsJson, paramsJson)
+		call.addRequest(paramsJson)
+	}
+	streamJson := "[" + strings.Join(requestsJson, ",") + "]"
+	if err := injectFault(ctx, fullMethod, methodFault(fullMethod)); err != nil {
+		return err
+	}
+	s := stubsMatcher.MatchStream(ctx, fullMethod, requestsJson)
+	call.stub = s
+	if s == nil {
+		log.Infof("NO mock response found for %s --> %s", fullMethod, streamJson)
+		return notMatchedError(fullMethod, stubsMatcher.NearStreamMisses(ctx, fullMethod, requestsJson))
+	}
+	if err := injectFault(ctx, fullMethod, s.Fault); err != nil {
+		return err
+	}
+	if s.Type == "forward" {
+		return forwardingNotSupported(fullMethod)
+	}
+	response, delay, err := stub.GetDelayedResponse(ctx, s, streamJson, resp)
+	if waitErr := wait(ctx, delay); waitErr != nil {
+		return waitErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(response); err != nil {
+		return err
+	}
+	call.addResponse(response)
+	return nil
+}
+
+// MockBidiStreamHandler runs the conversation of the stub matching a bidirectional streaming call against the stream.
+// The 'expect' steps are run in order as the client messages arrive, while the 'send' steps are sent unprompted from
+// the start of the stream. A 'close' step, or the end of the script, waits for the unprompted messages to be sent.
+var MockBidiStreamHandler = func(stream grpc.ServerStream, stubsMatcher stub.StubsMatcher, fullMethod string, newRequest func() interface{}, newResponse func() interface{}) (err error) {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	call := newJournalCall(ctx, fullMethod, true, true)
+	defer func() { call.record(err) }()
+	if err := injectFault(ctx, fullMethod, methodFault(fullMethod)); err != nil {
+		return err
+	}
+	s := stubsMatcher.MatchConversation(ctx, fullMethod)
+	call.stub = s
+	if s == nil {
+		log.Infof("NO mock conversation found for %s", fullMethod)
+		return notMatchedError(fullMethod, stubsMatcher.NearConversationMisses(ctx, fullMethod))
+	}
+	if err := injectFault(ctx, fullMethod, s.Fault); err != nil {
+		return err
+	}
+	stub.SetResponseMetadata(ctx, s.Response)
+	send := conversationSender(ctx, stream, call)
+	unprompted := sendUnprompted(ctx, s, send, newResponse)
+	requestJson := ""
+	for i, step := range s.Response.Conversation {
+		switch step.Type {
+		case "send":
+			continue
+		case "close":
+			if err := <-unprompted; err != nil {
+				return err
+			}
+			_, err := stub.GetConversationResponse(ctx, s, step, requestJson, newResponse)
+			return err
+		}
+		req := newRequest()
+		err := stream.RecvMsg(req)
+		if err == io.EOF {
+			return status.Errorf(codes.FailedPrecondition, "stream closed by the client at conversation step %d, which expected %s", i, step.Content)
+		}
+		if err != nil {
+			return err
+		}
+		requestJson, err = getRequestInJSON(req)
+		if err != nil {
+			logError(fullMethod, requestJson, err)
+			return err
+		}
+		call.addRequest(requestJson)
+		if !step.Matches(requestJson) {
+			log.Infof("Unexpected message at conversation step %d for %s --> %s", i, fullMethod, requestJson)
+			return status.Errorf(codes.FailedPrecondition, "unexpected message at conversation step %d: expected %s but received %s", i, step.Content, requestJson)
+		}
+		messages, err := stub.GetConversationResponse(ctx, s, step, requestJson, newResponse)
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			if err := send(message); err != nil {
+				return err
+			}
+		}
+	}
+	return <-unprompted
+}
+
+// forwardingNotSupported is the error ending the streaming calls matched by a 'forward' stub. Only unary calls are forwarded.
+func forwardingNotSupported(fullMethod string) error {
+	log.Warnf("A forward stub matched the call to %s but streaming calls can't be forwarded", fullMethod)
+	return status.Error(codes.Unimplemented, "forwarding is not supported for streaming methods")
+}
+
+// conversationSender returns the function sending the messages of a conversation, which can be called concurrently
+// by the 'expect' steps and the unprompted 'send' steps
+func conversationSender(ctx context.Context, stream grpc.ServerStream, call *journalCall) func(stub.StreamResponse) error {
+	var mutex sync.Mutex
+	return func(message stub.StreamResponse) error {
+		if err := wait(ctx, message.Delay); err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err := stream.SendMsg(message.Message); err != nil {
+			return err
+		}
+		call.addResponse(message.Message)
+		return nil
+	}
+}
+
+// sendUnprompted sends the messages of the 'send' steps in order, without waiting for the client.
+// The channel returned receives the error sending them, or nil, once they are all sent.
+func sendUnprompted(ctx context.Context, s *stub.Stub, send func(stub.StreamResponse) error, newResponse func() interface{}) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		for _, step := range s.Response.Conversation {
+			if step.Type != "send" {
+				continue
+			}
+			messages, err := stub.GetConversationResponse(ctx, s, step, "", newResponse)
+			if err != nil {
+				done <- err
+				return
+			}
+			for _, message := range messages {
+				if err := send(message); err != nil {
+					done <- err
+					return
+				}
+			}
+		}
+		done <- nil
+	}()
+	return done
+}
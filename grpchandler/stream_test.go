@@ -0,0 +1,297 @@
+package grpchandler
+
+import (
+	"context"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"io"
+	"net"
+	"testing"
+)
+
+const (
+	serverStreamMethod = "/test.Streams/ServerStream"
+	clientStreamMethod = "/test.Streams/ClientStream"
+	bidiStreamMethod   = "/test.Streams/BidiStream"
+)
+
+func newMessage() interface{} {
+	return new(structpb.Struct)
+}
+
+// streamsServiceDesc is a service with a method of each streaming type, served by the mock stream handlers
+func streamsServiceDesc(matcher stub.StubsMatcher) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Streams",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName: "ServerStream",
+				Handler: func(srv interface{}, stream grpc.ServerStream) error {
+					return MockServerStreamHandler(stream, matcher, serverStreamMethod, newMessage(), newMessage)
+				},
+				ServerStreams: true,
+			},
+			{
+				StreamName: "ClientStream",
+				Handler: func(srv interface{}, stream grpc.ServerStream) error {
+					return MockClientStreamHandler(stream, matcher, clientStreamMethod, newMessage, newMessage())
+				},
+				ClientStreams: true,
+			},
+			{
+				StreamName: "BidiStream",
+				Handler: func(srv interface{}, stream grpc.ServerStream) error {
+					return MockBidiStreamHandler(stream, matcher, bidiStreamMethod, newMessage, newMessage)
+				},
+				ServerStreams: true,
+				ClientStreams: true,
+			},
+		},
+	}
+}
+
+// startStreamsServer serves the stubs in an in-process server and returns a connection to it
+func startStreamsServer(t *testing.T, stubs ...*stub.Stub) *grpc.ClientConn {
+	store := stub.NewInMemoryStubsStore()
+	for _, s := range stubs {
+		assert.Nil(t, store.Add(s))
+	}
+	matcher := stub.NewStubsMatcher(store, stub.NewInMemoryScenariosStore())
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	server.RegisterService(streamsServiceDesc(matcher), struct{}{})
+	go server.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+func newStream(t *testing.T, conn *grpc.ClientConn, method string) grpc.ClientStream {
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	stream, err := conn.NewStream(context.Background(), desc, method)
+	assert.Nil(t, err)
+	return stream
+}
+
+func toStruct(t *testing.T, fields map[string]interface{}) *structpb.Struct {
+	message, err := structpb.NewStruct(fields)
+	assert.Nil(t, err)
+	return message
+}
+
+// receiveAll receives the messages of the stream until it ends and returns them with the status it ended with
+func receiveAll(stream grpc.ClientStream) ([]map[string]interface{}, error) {
+	messages := make([]map[string]interface{}, 0)
+	for {
+		message := new(structpb.Struct)
+		err := stream.RecvMsg(message)
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message.AsMap())
+	}
+}
+
+func TestMockServerStreamHandler(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: serverStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact", Content: `{"name":"a"}`},
+		Response: &stub.StubResponse{
+			Type: "stream",
+			Stream: []stub.StreamMessage{
+				{Content: `{"greeting":"hi"}`},
+				{Content: `{"greeting":"bye"}`, Delay: 10},
+			},
+			Error: &stub.ErrorResponse{Code: uint32(codes.Aborted), Message: "done"},
+		},
+	})
+
+	stream := newStream(t, conn, serverStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Equal(t, []map[string]interface{}{{"greeting": "hi"}, {"greeting": "bye"}}, messages)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, "done", status.Convert(err).Message())
+}
+
+func TestMockServerStreamHandler_NotMatched(t *testing.T) {
+	conn := startStreamsServer(t)
+
+	stream := newStream(t, conn, serverStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Empty(t, messages)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMockServerStreamHandler_Forward(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: serverStreamMethod,
+		Type:       "forward",
+		Request:    &stub.StubRequest{Match: "exact", Content: `{"name":"a"}`},
+		Forward:    &stub.StubForward{ServerAddress: "localhost:1"},
+	})
+
+	stream := newStream(t, conn, serverStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Empty(t, messages)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Equal(t, "forwarding is not supported for streaming methods", status.Convert(err).Message())
+}
+
+func TestMockClientStreamHandler(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: clientStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact", Stream: []stub.JsonString{`{"name":"a"}`, `{"name":"b"}`}, StreamMatch: "all"},
+		Response:   &stub.StubResponse{Type: "success", Content: `{"greeting":"hi a and b"}`},
+	})
+
+	stream := newStream(t, conn, clientStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "b"})))
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{{"greeting": "hi a and b"}}, messages)
+}
+
+func TestMockClientStreamHandler_Forward(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: clientStreamMethod,
+		Type:       "forward",
+		Request:    &stub.StubRequest{Match: "exact", Stream: []stub.JsonString{`{"name":"a"}`}, StreamMatch: "all"},
+		Forward:    &stub.StubForward{ServerAddress: "localhost:1"},
+	})
+
+	stream := newStream(t, conn, clientStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.CloseSend())
+	_, err := receiveAll(stream)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestMockClientStreamHandler_NotMatched(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: clientStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact", Stream: []stub.JsonString{`{"name":"a"}`, `{"name":"b"}`}, StreamMatch: "all"},
+		Response:   &stub.StubResponse{Type: "success", Content: `{"greeting":"hi a and b"}`},
+	})
+
+	stream := newStream(t, conn, clientStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Empty(t, messages)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "stream: expected 2 messages but got 1")
+}
+
+func TestMockBidiStreamHandler(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: bidiStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact"},
+		Response: &stub.StubResponse{
+			Type: "conversation",
+			Conversation: []stub.ConversationStep{
+				{Type: "expect", Match: "partial", Content: `{"name":"a"}`, Send: []stub.StreamMessage{{Content: `{"greeting":"hi a"}`}}},
+				{Type: "send", Send: []stub.StreamMessage{{Content: `{"greeting":"welcome"}`}, {Content: `{"greeting":"tick"}`, Delay: 50}}},
+				{Type: "close", Error: &stub.ErrorResponse{Code: uint32(codes.Aborted), Message: "bye"}},
+			},
+		},
+	})
+
+	stream := newStream(t, conn, bidiStreamMethod)
+	// the welcome message is sent unprompted, before the client sends anything
+	welcome := new(structpb.Struct)
+	assert.Nil(t, stream.RecvMsg(welcome))
+	assert.Equal(t, map[string]interface{}{"greeting": "welcome"}, welcome.AsMap())
+
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "a"})))
+	messages, err := receiveAll(stream)
+	assert.Equal(t, []map[string]interface{}{{"greeting": "hi a"}, {"greeting": "tick"}}, messages)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, "bye", status.Convert(err).Message())
+}
+
+func TestMockBidiStreamHandler_NotMatched(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: bidiStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact", Metadata: map[string][]string{"tenant": {"t1"}}},
+		Response: &stub.StubResponse{
+			Type:         "conversation",
+			Conversation: []stub.ConversationStep{{Type: "close"}},
+		},
+	})
+	SetNotMatchedCode(codes.Unimplemented)
+	defer SetNotMatchedCode(codes.NotFound)
+
+	stream := newStream(t, conn, bidiStreamMethod)
+	assert.Nil(t, stream.CloseSend())
+	messages, err := receiveAll(stream)
+	assert.Empty(t, messages)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "1) conversation: metadata tenant: expected [t1] but got []")
+}
+
+func TestMockBidiStreamHandler_UnexpectedMessage(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: bidiStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact"},
+		Response: &stub.StubResponse{
+			Type: "conversation",
+			Conversation: []stub.ConversationStep{
+				{Type: "expect", Match: "exact", Content: `{"name":"a"}`, Send: []stub.StreamMessage{{Content: `{"greeting":"hi a"}`}}},
+			},
+		},
+	})
+
+	stream := newStream(t, conn, bidiStreamMethod)
+	assert.Nil(t, stream.SendMsg(toStruct(t, map[string]interface{}{"name": "b"})))
+	messages, err := receiveAll(stream)
+	assert.Empty(t, messages)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestMockBidiStreamHandler_ClosedByClient(t *testing.T) {
+	conn := startStreamsServer(t, &stub.Stub{
+		FullMethod: bidiStreamMethod,
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact"},
+		Response: &stub.StubResponse{
+			Type: "conversation",
+			Conversation: []stub.ConversationStep{
+				{Type: "expect", Match: "exact", Content: `{"name":"a"}`, Send: []stub.StreamMessage{{Content: `{"greeting":"hi a"}`}}},
+			},
+		},
+	})
+
+	stream := newStream(t, conn, bidiStreamMethod)
+	assert.Nil(t, stream.CloseSend())
+	_, err := receiveAll(stream)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,29 @@
+package grpchandler
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+)
+
+var upstreamsStore stub.UpstreamsStore
+
+// SetUpstreamsStore sets the store of the upstreams the calls no stub matches are forwarded to
+func SetUpstreamsStore(store stub.UpstreamsStore) {
+	upstreamsStore = store
+}
+
+// upstreamStub returns a forward stub to the upstream of the method, or nil when the method has no upstream
+func upstreamStub(fullMethod, paramsJson string) *stub.Stub {
+	if upstreamsStore == nil {
+		return nil
+	}
+	forward := upstreamsStore.Resolve(fullMethod)
+	if forward == nil {
+		return nil
+	}
+	return &stub.Stub{
+		FullMethod: fullMethod,
+		Type:       "forward",
+		Request:    &stub.StubRequest{Match: "exact", Content: stub.JsonString(paramsJson)},
+		Forward:    forward,
+	}
+}
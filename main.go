@@ -85,6 +85,7 @@ func GenerateMain(gen *protogen.Plugin) {
 	file.P("func Start(restPort, grpcPort uint, tmpPath string) {")
 	file.P(bootstrapPackage.Ident("BootstrapServers"), "(tmpPath, restPort, grpcPort, MockServicesRegistrationCallback)")
 	file.P("}")
+	file.P("")
 	file.P("var MockServicesRegistrationCallback = func(stubsMatcher ", stubPackage.Ident("StubsMatcher"), ") ", grpcHandlerPackage.Ident("MockService"), " {")
 	file.P("return ", grpcHandlerPackage.Ident("NewCompositeMockService"), "([]", grpcHandlerPackage.Ident("MockService"), "{")
 	for _, f := range gen.Files {
@@ -157,6 +158,7 @@ func (m mockServicesGenerator) genService(service *protogen.Service) {
 	m.genMockServiceDefinition(service)
 	m.genMockServiceRegistrationFunction(service)
 	m.genGetSupportedMethodsFunction(service)
+	m.genGetMethodAliasesFunction(service)
 	m.genGetPayloadExamplesFunction(service)
 	m.genGetRequestInstance(service)
 	m.genGetResponseInstance(service)
@@ -225,6 +227,20 @@ func (m mockServicesGenerator) genGetSupportedMethodsFunction(service *protogen.
 	m.g.P()
 }
 
+func (m mockServicesGenerator) genGetMethodAliasesFunction(service *protogen.Service) {
+	m.g.P("func (mock *", unexport(m.getMockServiceName(service)), ") GetMethodAliases() map[string]string {")
+	m.g.P("return map[string]string{")
+	for _, method := range service.Methods {
+		if m.getGoFullMethodName(service, method) == m.getFullMethodName(service, method) {
+			continue
+		}
+		m.g.P(m.getGoFullMethodName(service, method), ": ", m.getFullMethodName(service, method), ",")
+	}
+	m.g.P("}")
+	m.g.P("}")
+	m.g.P()
+}
+
 func (m mockServicesGenerator) genGetPayloadExamplesFunction(service *protogen.Service) {
 	m.g.P("func (mock *", unexport(m.getMockServiceName(service)), ") GetPayloadExamples() []", stubPackage.Ident("Stub"), "{")
 	m.g.P("return []", stubPackage.Ident("Stub"), "{")
@@ -232,15 +248,8 @@ func (m mockServicesGenerator) genGetPayloadExamplesFunction(service *protogen.S
 		m.g.P("{")
 		m.g.P("FullMethod: ", m.getFullMethodName(service, method), ",")
 		m.g.P("Type: ", strconv.Quote("mock | forward"), ",")
-		m.g.P("Request: &", stubPackage.Ident("StubRequest"), " {")
-		m.g.P("Match: \"exact | partial\",")
-		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Input.GoIdent, "))", "),")
-		m.g.P("Metadata: make(map[string][]string, 0),")
-		m.g.P("},")
-		m.g.P("Response: &", stubPackage.Ident("StubResponse"), " {")
-		m.g.P("Type: ", strconv.Quote("success | error"), ", ")
-		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Output.GoIdent, "))", "),")
-		m.g.P("},")
+		m.genRequestExample(method)
+		m.genResponseExample(method)
 		m.g.P("Forward: &", stubPackage.Ident("StubForward"), " {")
 		m.g.P("ServerAddress: ", strconv.Quote("yourserver:port"), ",")
 		m.g.P("Record: true,")
@@ -252,6 +261,74 @@ func (m mockServicesGenerator) genGetPayloadExamplesFunction(service *protogen.S
 	m.g.P("")
 }
 
+func (m mockServicesGenerator) genRequestExample(method *protogen.Method) {
+	m.g.P("Request: &", stubPackage.Ident("StubRequest"), " {")
+	if method.Desc.IsStreamingClient() && method.Desc.IsStreamingServer() {
+		// conversations are selected by metadata only
+		m.g.P("Metadata: make(map[string][]string, 0),")
+		m.g.P("},")
+		return
+	}
+	m.g.P("Match: \"exact | partial\",")
+	if method.Desc.IsStreamingClient() {
+		m.g.P("Stream: []", stubPackage.Ident("JsonString"), "{")
+		m.g.P(stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Input.GoIdent, "))", "),")
+		m.g.P("},")
+		m.g.P("StreamMatch: \"all | last | any\",")
+	} else {
+		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Input.GoIdent, "))", "),")
+	}
+	m.g.P("Metadata: make(map[string][]string, 0),")
+	m.g.P("},")
+}
+
+func (m mockServicesGenerator) genResponseExample(method *protogen.Method) {
+	m.g.P("Response: &", stubPackage.Ident("StubResponse"), " {")
+	if method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
+		m.g.P("Type: ", strconv.Quote("stream | success | error"), ", ")
+		m.g.P("Stream: []", stubPackage.Ident("StreamMessage"), "{")
+		m.g.P("{")
+		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Output.GoIdent, "))", "),")
+		m.g.P("Delay: 0,")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("},")
+		return
+	}
+	if method.Desc.IsStreamingServer() && method.Desc.IsStreamingClient() {
+		m.g.P("Type: ", strconv.Quote("conversation"), ", ")
+		m.g.P("Conversation: []", stubPackage.Ident("ConversationStep"), "{")
+		m.g.P("{")
+		m.g.P("Type: ", strconv.Quote("expect"), ",")
+		m.g.P("Match: \"exact | partial\",")
+		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Input.GoIdent, "))", "),")
+		m.g.P("Send: []", stubPackage.Ident("StreamMessage"), "{")
+		m.g.P("{")
+		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Output.GoIdent, "))", "),")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("{")
+		m.g.P("Type: ", strconv.Quote("send"), ",")
+		m.g.P("Send: []", stubPackage.Ident("StreamMessage"), "{")
+		m.g.P("{")
+		m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Output.GoIdent, "))", "),")
+		m.g.P("Delay: 1000,")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("{")
+		m.g.P("Type: ", strconv.Quote("close"), ",")
+		m.g.P("},")
+		m.g.P("},")
+		m.g.P("},")
+		return
+	}
+	m.g.P("Type: ", strconv.Quote("success | error"), ", ")
+	m.g.P("Content: ", stubPackage.Ident("JsonString"), "(", stubPackage.Ident("CreateStubExample"), "(new(", method.Output.GoIdent, "))", "),")
+	m.g.P("},")
+}
+
 func (m mockServicesGenerator) genGetRequestInstance(service *protogen.Service) {
 	m.g.P("func (mock *", unexport(m.getMockServiceName(service)), ") GetRequestInstance(methodName string) ", protoPackage.Ident("Message"), " {")
 	m.g.P("switch methodName {")
@@ -330,9 +407,30 @@ func (m mockServicesGenerator) genMockMethodHandler(service *protogen.Service, m
 		m.g.P()
 		return
 	}
+	if !method.Desc.IsStreamingClient() {
+		m.g.P("func ", hname, "(srv interface{}, stream ", grpcPackage.Ident("ServerStream"), ") error {")
+		m.g.P("in := new(", method.Input.GoIdent, ")")
+		m.g.P("fullMethod := ", m.getFullMethodName(service, method))
+		m.g.P("stubsMatcher := (srv).(*", unexport(m.getMockServiceName(service)), ").StubsMatcher")
+		m.g.P("return ", grpcHandlerPackage.Ident("MockServerStreamHandler"), "(stream, stubsMatcher, fullMethod, in, func() interface{} { return new(", method.Output.GoIdent, ") })")
+		m.g.P("}")
+		m.g.P()
+		return
+	}
+	if !method.Desc.IsStreamingServer() {
+		m.g.P("func ", hname, "(srv interface{}, stream ", grpcPackage.Ident("ServerStream"), ") error {")
+		m.g.P("out := new(", method.Output.GoIdent, ")")
+		m.g.P("fullMethod := ", m.getFullMethodName(service, method))
+		m.g.P("stubsMatcher := (srv).(*", unexport(m.getMockServiceName(service)), ").StubsMatcher")
+		m.g.P("return ", grpcHandlerPackage.Ident("MockClientStreamHandler"), "(stream, stubsMatcher, fullMethod, func() interface{} { return new(", method.Input.GoIdent, ") }, out)")
+		m.g.P("}")
+		m.g.P()
+		return
+	}
 	m.g.P("func ", hname, "(srv interface{}, stream ", grpcPackage.Ident("ServerStream"), ") error {")
-	m.g.P("// Mock not implemented for streaming")
-	m.g.P("return ", fmtPackage.Ident("Errorf"), "(\"mock not implemented for streaming\")")
+	m.g.P("fullMethod := ", m.getFullMethodName(service, method))
+	m.g.P("stubsMatcher := (srv).(*", unexport(m.getMockServiceName(service)), ").StubsMatcher")
+	m.g.P("return ", grpcHandlerPackage.Ident("MockBidiStreamHandler"), "(stream, stubsMatcher, fullMethod, func() interface{} { return new(", method.Input.GoIdent, ") }, func() interface{} { return new(", method.Output.GoIdent, ") })")
 	m.g.P("}")
 	m.g.P()
 }
@@ -364,12 +462,16 @@ func (m mockServicesGenerator) genIsValid(service *protogen.Service) {
 
 func (m mockServicesGenerator) genForwardRequest(service *protogen.Service) {
 
-	m.g.P("func(mock *", unexport(m.getMockServiceName(service)), ") ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}) (interface{}, error) {")
+	m.g.P("func(mock *", unexport(m.getMockServiceName(service)), ") ForwardRequest(conn grpc.ClientConnInterface, ctx context.Context, methodName string, req interface{}, opts ...grpc.CallOption) (interface{}, error) {")
 	m.g.P("client := New", service.Desc.Name(), "Client(conn)")
 	m.g.P("switch methodName {")
 	for _, method := range service.Methods {
 		m.g.P("case ", m.getFullMethodName(service, method), ":")
-		m.g.P("return client.", method.GoName, "(ctx, req.(*", method.Input.GoIdent, "))")
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			m.g.P("return nil, ", statusPackage.Ident("Error"), "(", codesPackage.Ident("Unimplemented"), ", \"forwarding is not supported for streaming methods\")")
+			continue
+		}
+		m.g.P("return client.", method.GoName, "(ctx, req.(*", method.Input.GoIdent, "), opts...)")
 	}
 	m.g.P("}")
 	m.g.P("return nil, nil")
@@ -412,7 +514,20 @@ func (m mockServicesGenerator) genRemoteCalls(service *protogen.Service, method
 	m.g.P("func (c ", callName, ") Error(code ", codesPackage.Ident("Code"), ", message string) error {")
 	m.g.P("return c.client.remoteMockClient.AddStub(", methodFullName, ", c.ctx, c.req, nil, ", statusPackage.Ident("New"), "(code, message))")
 	m.g.P("}")
-
+	m.g.P("")
+	if method.Desc.IsStreamingClient() {
+		return // the calls of client and bidirectional streaming methods are made of several requests
+	}
+	m.g.P("// ReturnSequence serves the responses in order, one per call. The last one is repeated once all were served.")
+	m.g.P("// Use ", remotePackage.Ident("WithResponse"), " and ", remotePackage.Ident("WithError"), " to create the responses.")
+	m.g.P("func (c ", callName, ") ReturnSequence(responses ...", remotePackage.Ident("SequenceResponse"), ") error {")
+	m.g.P("return c.client.remoteMockClient.AddSequenceStub(", methodFullName, ", c.ctx, c.req, responses)")
+	m.g.P("}")
+	m.g.P("")
+	m.g.P("// Verify", method.GoName, " checks the calls the mock server received with the request and the metadata in ctx. A nil request matches any request made with the metadata.")
+	m.g.P("func (c ", remoteMockClientName, ") Verify", method.GoName, "(ctx ", contextPackage.Ident("Context"), ", request *", method.Input.GoIdent, ") ", remotePackage.Ident("CallsVerification"), " {")
+	m.g.P("return ", remotePackage.Ident("NewCallsVerification"), "(c.remoteMockClient, ", methodFullName, ", ctx, request)")
+	m.g.P("}")
 	m.g.P("")
 }
 
@@ -426,11 +541,13 @@ func (m mockServicesGenerator) genRemoteMockClientClear(service *protogen.Servic
 
 func (m mockServicesGenerator) genRemoteMockClient(service *protogen.Service) {
 	remoteMockClientName := m.getRemoteMockClientName(service)
+	m.g.P("// New", remoteMockClientName, " creates a client of the REST API of the mock server. Use ", remotePackage.Ident("WithTLS"), " to connect over https.")
 	m.g.P("func New" + remoteMockClientName + "(")
 	m.g.P("host string,")
 	m.g.P("port int,")
+	m.g.P("options ...", remotePackage.Ident("Option"), ",")
 	m.g.P(") " + remoteMockClientName + "{")
-	m.g.P("client := ", remotePackage.Ident("New"), "(host, port)")
+	m.g.P("client := ", remotePackage.Ident("New"), "(host, port, options...)")
 	m.g.P("return " + remoteMockClientName + "{")
 	m.g.P("host: host,")
 	m.g.P("port: port,")
@@ -447,7 +564,13 @@ func (m mockServicesGenerator) genRemoteMockClient(service *protogen.Service) {
 	m.genRemoteMockClientClear(service)
 }
 
+// getFullMethodName returns the wire-level name gRPC routes the method on
 func (m mockServicesGenerator) getFullMethodName(service *protogen.Service, method *protogen.Method) string {
+	return strconv.Quote(fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name()))
+}
+
+// getGoFullMethodName returns the full method name built from the Go name of the method, used by previous versions of the plugin
+func (m mockServicesGenerator) getGoFullMethodName(service *protogen.Service, method *protogen.Method) string {
 	return strconv.Quote(fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.GoName))
 }
 
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+	"strings"
+	"testing"
+)
+
+// newTestPlugin creates a plugin for a file with a Greeter service. The name of the method say_hello isn't its Go name.
+func newTestPlugin(t *testing.T) *protogen.Plugin {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("greeter.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/greeter;greeter")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Empty")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Greeter"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: proto.String("say_hello"), InputType: proto.String(".test.Empty"), OutputType: proto.String(".test.Empty")},
+					{Name: proto.String("Ping"), InputType: proto.String(".test.Empty"), OutputType: proto.String(".test.Empty")},
+					{Name: proto.String("Upload"), InputType: proto.String(".test.Empty"), OutputType: proto.String(".test.Empty"), ClientStreaming: proto.Bool(true)},
+				},
+			},
+		},
+	}
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"greeter.proto"},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return gen
+}
+
+// generatedContent returns the content of the generated file whose name ends with the name
+func generatedContent(t *testing.T, gen *protogen.Plugin, name string) string {
+	for _, file := range gen.Response().File {
+		if strings.HasSuffix(file.GetName(), name) {
+			return file.GetContent()
+		}
+	}
+	t.Fatalf("file %s not generated", name)
+	return ""
+}
+
+func TestGenerateFile_MethodAliases(t *testing.T) {
+	gen := newTestPlugin(t)
+	GenerateFile(gen, gen.Files[0])
+	content := generatedContent(t, gen, "greeter.mock.pb.go")
+
+	start := strings.Index(content, ") GetMethodAliases() map[string]string {")
+	if !assert.NotEqual(t, -1, start) {
+		return
+	}
+	aliases := content[start : start+strings.Index(content[start:], "\n}\n")]
+	assert.Contains(t, aliases, `"/test.Greeter/SayHello": "/test.Greeter/say_hello",`)
+	assert.NotContains(t, aliases, "Ping")
+}
+
+func TestGenerateFile_ClientStreamingRemoteCalls(t *testing.T) {
+	gen := newTestPlugin(t)
+	GenerateFile(gen, gen.Files[0])
+	content := generatedContent(t, gen, "greeter.mock.pb.go")
+
+	assert.Contains(t, content, ") Return(response *Empty) error {")
+	assert.Contains(t, content, "func (c GreeterRemoteMockClient) VerifyPing(")
+	assert.NotContains(t, content, "func (c GreeterRemoteMockClient) VerifyUpload(")
+	assert.Equal(t, 2, strings.Count(content, ") ReturnSequence(responses ...remote.SequenceResponse) error {"))
+}
+
+func TestGenerateFile_RemoteMockClientOptions(t *testing.T) {
+	gen := newTestPlugin(t)
+	GenerateFile(gen, gen.Files[0])
+	content := generatedContent(t, gen, "greeter.mock.pb.go")
+
+	assert.Contains(t, content, "func NewGreeterRemoteMockClient(\n\thost string,\n\tport int,\n\toptions ...remote.Option,\n) GreeterRemoteMockClient {")
+	assert.Contains(t, content, "client := remote.New(host, port, options...)")
+}
+
+func TestGenerateMain_TLSFromEnv(t *testing.T) {
+	gen := newTestPlugin(t)
+	GenerateMain(gen)
+	content := generatedContent(t, gen, "main.go")
+
+	// the TLS configuration is read from the environment by the bootstrap package
+	assert.Contains(t, content, "bootstrap.BootstrapServers(tmpPath, restPort, grpcPort, MockServicesRegistrationCallback)")
+	assert.NotContains(t, content, "tlsFromEnv")
+}
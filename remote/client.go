@@ -3,11 +3,13 @@ package remote
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	httputils "github.com/carvalhorr/goutils/http"
 	"github.com/carvalhorr/protoc-gen-mock/stub"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -24,18 +26,66 @@ type MockServerClient interface {
 		error *status.Status,
 	) error
 
+	AddSequenceStub(
+		fullMethod string,
+		ctx context.Context,
+		req proto.Message,
+		responses []SequenceResponse,
+	) error
+
 	DeleteAllStubs() error
+
+	VerifyCalls(
+		fullMethod string,
+		ctx context.Context,
+		req proto.Message,
+	) (int, error)
+}
+
+// SequenceResponse is one of the responses served in order by a sequence stub. Either Response or Error must be set.
+type SequenceResponse struct {
+	Response proto.Message
+	Error    *status.Status
+}
+
+// WithResponse creates a successful SequenceResponse
+func WithResponse(resp proto.Message) SequenceResponse {
+	return SequenceResponse{Response: resp}
+}
+
+// WithError creates a SequenceResponse failing with the code and message provided
+func WithError(code codes.Code, message string) SequenceResponse {
+	return SequenceResponse{Error: status.New(code, message)}
 }
 
+// Option changes how the client connects to the REST API of the mock server
+type Option func(c *client)
+
+// WithTLS connects to the mock server over https, verifying its certificate with the TLS configuration. The RootCAs
+// of the configuration trust a self-signed certificate, and its Certificates are sent to a server using mTLS.
+func WithTLS(config *tls.Config) Option {
+	return func(c *client) {
+		c.scheme = "https"
+		c.HttpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	}
+}
+
+// New creates a client of the REST API of the mock server. It uses plain http unless WithTLS is given.
 func New(
 	host string,
 	port int,
+	options ...Option,
 ) MockServerClient {
-	return &client{
+	c := &client{
 		HttpClient: &http.Client{},
 		host:       host,
 		port:       port,
+		scheme:     "http",
 	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
 }
 
 type client struct {
@@ -46,6 +96,16 @@ type client struct {
 	HttpClient httputils.Client
 	host       string
 	port       int
+	scheme     string // http | https
+}
+
+// url returns the URL of the path in the REST API of the mock server
+func (c *client) url(path string) string {
+	scheme := c.scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, c.host, c.port, path)
 }
 
 func (c *client) AddStub(
@@ -73,12 +133,51 @@ func (c *client) AddStub(
 		},
 		Forward: nil,
 	}
+	return c.addStub(s)
+}
+
+// AddSequenceStub adds a stub serving the responses in order, one per call. The last one is repeated once all were served.
+func (c *client) AddSequenceStub(
+	fullMethod string,
+	ctx context.Context,
+	req proto.Message,
+	responses []SequenceResponse,
+) error {
+	sequence := &stub.ResponseSequence{
+		Responses: make([]*stub.StubResponse, 0, len(responses)),
+		Exhausted: "repeat",
+	}
+	for _, response := range responses {
+		sequence.Responses = append(sequence.Responses, &stub.StubResponse{
+			Type:    getResponseType(response.Response, response.Error),
+			Content: toJsonString(response.Response),
+			Error:   toErrorResponse(response.Error),
+		})
+	}
+	s := &stub.Stub{
+		FullMethod: fullMethod,
+		Type:       "mock",
+		Request: &stub.StubRequest{
+			Match:    "exact",
+			Content:  toJsonString(req),
+			Metadata: getMetadata(ctx),
+		},
+		Response: &stub.StubResponse{
+			Type:     "sequence",
+			Sequence: sequence,
+		},
+		Forward: nil,
+	}
+	return c.addStub(s)
+}
+
+func (c *client) addStub(s *stub.Stub) error {
 	b, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 	writer := bytes.NewBuffer(b)
-	r, e := c.HttpClient.Post(fmt.Sprintf("http://%s:%d/stubs", c.host, c.port), "application/json", writer)
+	r, e := c.HttpClient.Post(c.url("/stubs"), "application/json", writer)
 	if e != nil {
 		return e
 	}
@@ -87,7 +186,7 @@ func (c *client) AddStub(
 }
 
 func (c *client) DeleteAllStubs() error {
-	deleteRequest, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://%s:%d/stubs", c.host, c.port), bytes.NewReader([]byte{}))
+	deleteRequest, err := http.NewRequest(http.MethodDelete, c.url("/stubs"), bytes.NewReader([]byte{}))
 	if err != nil {
 		return err
 	}
@@ -102,6 +201,47 @@ func (c *client) DeleteAllStubs() error {
 	return fmt.Errorf("error: status %s", resp.Status)
 }
 
+// VerifyCalls returns the number of calls the mock server received for the method with a request equal to req
+// and the metadata in ctx. Any request matches when req is nil, but the metadata in ctx still has to.
+func (c *client) VerifyCalls(
+	fullMethod string,
+	ctx context.Context,
+	req proto.Message,
+) (int, error) {
+	verification := &stub.Verification{FullMethod: fullMethod}
+	if req != nil && req.ProtoReflect().IsValid() {
+		verification.Request = &stub.StubRequest{
+			Match:    "exact",
+			Content:  toJsonString(req),
+			Metadata: getMetadata(ctx),
+		}
+	} else if md, ok := metadata.FromOutgoingContext(ctx); ok && md.Len() > 0 {
+		// an empty object partially matches any request
+		verification.Request = &stub.StubRequest{
+			Match:    "partial",
+			Content:  "{}",
+			Metadata: getMetadata(ctx),
+		}
+	}
+	b, err := json.Marshal(verification)
+	if err != nil {
+		return 0, err
+	}
+	r, err := c.HttpClient.Post(c.url("/requests/verify"), "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("error: status %s", r.Status)
+	}
+	result := new(stub.VerificationResult)
+	if err := json.NewDecoder(r.Body).Decode(result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
 func getMetadata(ctx context.Context) map[string][]string {
 	md, ok := metadata.FromOutgoingContext(ctx)
 	if !ok {
@@ -4,14 +4,21 @@ package remote
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	httputils "github.com/carvalhorr/goutils/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -126,3 +133,126 @@ func TestClient_AddStub_HttpError(t *testing.T) {
 	err := client.AddStub("", context.Background(), &Request{}, nil, status.New(codes.AlreadyExists, "error"))
 	assert.EqualError(t, err, "http error")
 }
+
+func TestClient_AddSequenceStub_Success(t *testing.T) {
+	mockHttpClient := new(httputils.MockClient)
+	mockHttpClient.On("Post",
+		mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+		Status:     "OK",
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader("OK")),
+	}, nil)
+	client := &client{
+		HttpClient: mockHttpClient,
+	}
+	err := client.AddSequenceStub("", context.Background(), &Request{}, []SequenceResponse{
+		WithError(codes.Unavailable, "unavailable"),
+		WithResponse(&Response{}),
+	})
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(mockHttpClient.Calls[0].Arguments.Get(2).(io.Reader))
+	assert.Contains(t, string(body), `"sequence":{"responses":[{"type":"error","content":{},"error":{"code":14,"message":"unavailable","details":null}},{"type":"success","content":{},"error":null}],"exhausted":"repeat"}`)
+}
+
+func TestClient_VerifyCalls_Success(t *testing.T) {
+	mockHttpClient := new(httputils.MockClient)
+	mockHttpClient.On("Post",
+		mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+		Status:     "OK",
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"count":2}`)),
+	}, nil)
+	client := &client{
+		HttpClient: mockHttpClient,
+	}
+	count, err := client.VerifyCalls("method1", context.Background(), &Request{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "http://:0/requests/verify", mockHttpClient.Calls[0].Arguments.Get(0))
+}
+
+func TestClient_VerifyCalls_NilRequest(t *testing.T) {
+	mockHttpClient := new(httputils.MockClient)
+	for i := 0; i < 2; i++ {
+		mockHttpClient.On("Post",
+			mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+			Status:     "OK",
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"count":1}`)),
+		}, nil).Once()
+	}
+	client := &client{
+		HttpClient: mockHttpClient,
+	}
+
+	_, err := client.VerifyCalls("method1", context.Background(), nil)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(mockHttpClient.Calls[0].Arguments.Get(2).(io.Reader))
+	assert.Equal(t, `{"fullMethod":"method1"}`, string(body))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "tenant", "x")
+	_, err = client.VerifyCalls("method1", ctx, nil)
+	assert.Nil(t, err)
+	body, _ = ioutil.ReadAll(mockHttpClient.Calls[1].Arguments.Get(2).(io.Reader))
+	assert.Equal(t, `{"fullMethod":"method1","request":{"match":"partial","content":{},"metadata":{"tenant":["x"]}}}`, string(body))
+}
+
+func TestClient_VerifyCalls_StatusNot200(t *testing.T) {
+	mockHttpClient := new(httputils.MockClient)
+	mockHttpClient.On("Post",
+		mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+		Status:     "400 - mocked status",
+		StatusCode: 400,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil)
+	client := &client{
+		HttpClient: mockHttpClient,
+	}
+	_, err := client.VerifyCalls("method1", context.Background(), nil)
+	assert.EqualError(t, err, "error: status 400 - mocked status")
+}
+
+func TestCallsVerification_Times(t *testing.T) {
+	mockHttpClient := new(httputils.MockClient)
+	mockHttpClient.On("Post",
+		mock.Anything, mock.Anything, mock.Anything).Return(&http.Response{
+		Status:     "OK",
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"count":1}`)),
+	}, nil)
+	client := &client{
+		HttpClient: mockHttpClient,
+	}
+	err := NewCallsVerification(client, "method1", context.Background(), &Request{}).Never()
+	assert.EqualError(t, err, "expected method1 to be called 0 times but it was called 1 times")
+}
+
+func TestNew(t *testing.T) {
+	c := New("localhost", 1068).(*client)
+	assert.Equal(t, "http://localhost:1068/stubs", c.url("/stubs"))
+}
+
+func TestNew_WithTLS(t *testing.T) {
+	var method, path string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		method, path = request.Method, request.URL.Path
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.Nil(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	c := New(serverURL.Hostname(), port, WithTLS(&tls.Config{RootCAs: roots})).(*client)
+	assert.Equal(t, fmt.Sprintf("https://%s:%d/stubs", serverURL.Hostname(), port), c.url("/stubs"))
+	assert.Nil(t, c.DeleteAllStubs())
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/stubs", path)
+
+	// the server certificate isn't trusted without the CA
+	untrusted := New(serverURL.Hostname(), port, WithTLS(&tls.Config{}))
+	assert.NotNil(t, untrusted.DeleteAllStubs())
+}
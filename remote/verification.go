@@ -0,0 +1,55 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// CallsVerification checks how many times the mock server was called for a method with a request
+type CallsVerification struct {
+	client     MockServerClient
+	fullMethod string
+	ctx        context.Context
+	req        proto.Message
+}
+
+// NewCallsVerification creates a verification of the calls received for the method with a request equal to req
+// and the metadata in ctx. Any request matches when req is nil.
+func NewCallsVerification(client MockServerClient, fullMethod string, ctx context.Context, req proto.Message) CallsVerification {
+	return CallsVerification{
+		client:     client,
+		fullMethod: fullMethod,
+		ctx:        ctx,
+		req:        req,
+	}
+}
+
+// Times fails unless the method was called exactly n times with the request
+func (v CallsVerification) Times(n int) error {
+	count, err := v.client.VerifyCalls(v.fullMethod, v.ctx, v.req)
+	if err != nil {
+		return err
+	}
+	if count != n {
+		return fmt.Errorf("expected %s to be called %d times but it was called %d times", v.fullMethod, n, count)
+	}
+	return nil
+}
+
+// AtLeast fails unless the method was called n or more times with the request
+func (v CallsVerification) AtLeast(n int) error {
+	count, err := v.client.VerifyCalls(v.fullMethod, v.ctx, v.req)
+	if err != nil {
+		return err
+	}
+	if count < n {
+		return fmt.Errorf("expected %s to be called at least %d times but it was called %d times", v.fullMethod, n, count)
+	}
+	return nil
+}
+
+// Never fails if the method was called with the request
+func (v CallsVerification) Never() error {
+	return v.Times(0)
+}
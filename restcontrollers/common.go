@@ -3,6 +3,7 @@ package restcontrollers
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 )
@@ -33,7 +34,7 @@ func writeResponse(writer http.ResponseWriter, respponse interface{}) error {
 }
 
 func writeResponseWithCode(writer http.ResponseWriter, respponse interface{}, code int) error {
-	responseJSONBytes, err := json.Marshal(respponse)
+	responseJSONBytes, err := json.Marshal(redactSecrets(respponse))
 	if err != nil {
 		log.Errorf("Unexpected error while writing response in JSON. Error %s", err.Error())
 		return fmt.Errorf("error writing response to JSON")
@@ -65,3 +66,44 @@ func writeErrorResponse(writer http.ResponseWriter, code int, message string) {
 		log.Errorf("Error writing http response: Error %s", writeErr.Error())
 	}
 }
+
+// redactSecrets returns a copy of the stubs, upstreams, journal entries or near misses without the bearer tokens of
+// their forwards, which the REST API never shows nor logs. Other values are returned as they are.
+func redactSecrets(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case *stub.Stub:
+		return typed.Redacted()
+	case []*stub.Stub:
+		stubs := make([]*stub.Stub, 0, len(typed))
+		for _, s := range typed {
+			stubs = append(stubs, s.Redacted())
+		}
+		return stubs
+	case *stub.Upstream:
+		if typed == nil {
+			return typed
+		}
+		return stub.Upstream{Target: typed.Target, Forward: typed.Forward.Redacted()}
+	case []stub.Upstream:
+		upstreams := make([]stub.Upstream, 0, len(typed))
+		for _, upstream := range typed {
+			upstreams = append(upstreams, stub.Upstream{Target: upstream.Target, Forward: upstream.Forward.Redacted()})
+		}
+		return upstreams
+	case []stub.JournalEntry:
+		entries := make([]stub.JournalEntry, 0, len(typed))
+		for _, entry := range typed {
+			entry.Stub = entry.Stub.Redacted()
+			entries = append(entries, entry)
+		}
+		return entries
+	case []stub.NearMiss:
+		nearMisses := make([]stub.NearMiss, 0, len(typed))
+		for _, nearMiss := range typed {
+			nearMiss.Stub = nearMiss.Stub.Redacted()
+			nearMisses = append(nearMisses, nearMiss)
+		}
+		return nearMisses
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+package restcontrollers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"net/http"
+)
+
+type DiagnosticsController struct {
+	StubsMatcher stub.StubsMatcher
+	Service      grpchandler.MockService
+}
+
+func (c DiagnosticsController) GetHandlers() []RESTHandler {
+	return []RESTHandler{
+		{
+			Name:    "DiagnoseRequest",
+			Path:    "",
+			Methods: []string{http.MethodPost},
+			Handler: c.diagnoseRequestHandler,
+		},
+	}
+}
+
+func (c DiagnosticsController) GetPath() string {
+	return "/diagnostics"
+}
+
+// diagnoseRequestHandler lists the stubs closest to matching the call in the payload and how they differ from it
+func (c DiagnosticsController) diagnoseRequestHandler(writer http.ResponseWriter, request *http.Request) {
+	diagnosticsRequest, err := readDiagnosticsRequestFromRequestBody(request)
+	if err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to diagnose request failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"request": toJSON(diagnosticsRequest)}).
+		Info("REST: received call to diagnose request")
+
+	if diagnosticsRequest.FullMethod == emptyString {
+		writeErrorResponse(writer, http.StatusBadRequest, "Full method name can't be empty")
+		return
+	}
+	diagnosticsRequest.FullMethod = grpchandler.ResolveMethod(c.Service, diagnosticsRequest.FullMethod)
+
+	md := metadata.MD{}
+	for key, values := range diagnosticsRequest.Metadata {
+		md.Append(key, values...)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	nearMisses := c.StubsMatcher.NearMisses(ctx, diagnosticsRequest.FullMethod, diagnosticsRequest.Request.String())
+	writeErr := writeResponse(writer, nearMisses)
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+func readDiagnosticsRequestFromRequestBody(request *http.Request) (*stub.DiagnosticsRequest, error) {
+	bodyData, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorf("Unexpected error while reading diagnostics request from the request. Error %s", err.Error())
+		return nil, fmt.Errorf("could not read diagnostics request in payload")
+	}
+	defer request.Body.Close()
+
+	diagnosticsRequest := new(stub.DiagnosticsRequest)
+	unmarshalErr := json.Unmarshal(bodyData, diagnosticsRequest)
+	if unmarshalErr != nil {
+		log.Errorf("Unexpected error while reading diagnostics request from the request. Error %s", unmarshalErr.Error())
+		return nil, fmt.Errorf("could not read diagnostics request in payload")
+	}
+
+	return diagnosticsRequest, nil
+}
@@ -0,0 +1,69 @@
+package restcontrollers
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsController_GetPath(t *testing.T) {
+	ctrl := DiagnosticsController{}
+
+	assert.Equal(t, "/diagnostics", ctrl.GetPath())
+}
+
+func TestDiagnosticsController_GetHandlers(t *testing.T) {
+	ctrl := DiagnosticsController{}
+
+	assert.Equal(t, 1, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "DiagnoseRequest"), http.MethodPost)
+}
+
+func TestDiagnosticsController_diagnoseRequestHandler(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	stubsStore.Add(&stub.Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "partial", Content: "{\"name\":\"a\"}", Metadata: map[string][]string{"tenant": {"t1"}}},
+		Response:   &stub.StubResponse{Type: "success", Content: "{}"},
+	})
+	ctrl := DiagnosticsController{
+		StubsMatcher: stub.NewStubsMatcher(stubsStore, stub.NewInMemoryScenariosStore()),
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/diagnostics", strings.NewReader(`{"fullMethod":"method1","request":{"name":"b"},"metadata":{"Tenant":["t1"]}}`))
+	findHandler(ctrl.GetHandlers(), "DiagnoseRequest").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"differences\":[\"name: expected \\\"a\\\" but got \\\"b\\\"\"]")
+}
+
+func TestDiagnosticsController_diagnoseRequestHandler_MissingMethod(t *testing.T) {
+	ctrl := DiagnosticsController{}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/diagnostics", strings.NewReader(`{"request":{}}`))
+	findHandler(ctrl.GetHandlers(), "DiagnoseRequest").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Full method name can't be empty", response.Body.String())
+}
+
+func TestDiagnosticsController_diagnoseRequestHandler_MethodAlias(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	stubsStore.Add(&stub.Stub{
+		FullMethod: "/pkg.Service/method",
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "partial", Content: "{\"name\":\"a\"}"},
+		Response:   &stub.StubResponse{Type: "success", Content: "{}"},
+	})
+	ctrl := DiagnosticsController{
+		StubsMatcher: stub.NewStubsMatcher(stubsStore, stub.NewInMemoryScenariosStore()),
+		Service:      aliasedMockService{},
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/diagnostics", strings.NewReader(`{"fullMethod":"/pkg.Service/Method","request":{"name":"b"}}`))
+	findHandler(ctrl.GetHandlers(), "DiagnoseRequest").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"differences\":[\"name: expected \\\"a\\\" but got \\\"b\\\"\"]")
+}
@@ -0,0 +1,129 @@
+package restcontrollers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type FaultsController struct {
+	FaultsStore stub.FaultsStore
+	Service     grpchandler.MockService
+}
+
+// RandomSeed is the payload to seed the faults, delays and templates of the stubs
+type RandomSeed struct {
+	Seed int64 `json:"seed"`
+}
+
+func (c FaultsController) GetHandlers() []RESTHandler {
+	return []RESTHandler{
+		{
+			Name:    "GetFaults",
+			Path:    "",
+			Methods: []string{http.MethodGet},
+			Handler: c.getFaultsHandler,
+		},
+		{
+			Name:    "SetFault",
+			Path:    "",
+			Methods: []string{http.MethodPut},
+			Handler: c.setFaultHandler,
+		},
+		{
+			Name:    "DeleteFaults",
+			Path:    "",
+			Methods: []string{http.MethodDelete},
+			Handler: c.deleteFaultsHandler,
+		},
+		{
+			Name:    "SetRandomSeed",
+			Path:    "/seed",
+			Methods: []string{http.MethodPut},
+			Handler: c.setRandomSeedHandler,
+		},
+	}
+}
+
+func (c FaultsController) GetPath() string {
+	return "/faults"
+}
+
+// getFaultsHandler lists the faults applied to all the calls of a method
+func (c FaultsController) getFaultsHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info("REST: received call to get faults")
+
+	writeErr := writeResponse(writer, c.FaultsStore.GetAll())
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+// setFaultHandler sets the fault applied to all the calls of a method, replacing the previous one
+func (c FaultsController) setFaultHandler(writer http.ResponseWriter, request *http.Request) {
+	methodFault := new(stub.MethodFault)
+	if err := readFromRequestBody(request, methodFault, "fault"); err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to set fault failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"fault": toJSON(methodFault)}).
+		Info("REST: received call to set fault")
+
+	if isValid, errorMessages := methodFault.IsValid(); !isValid {
+		writeErrorResponse(writer, http.StatusBadRequest, strings.Join(errorMessages, " "))
+		return
+	}
+	methodFault.FullMethod = grpchandler.ResolveMethod(c.Service, methodFault.FullMethod)
+
+	c.FaultsStore.Set(methodFault.FullMethod, methodFault.Fault)
+	writeSuccessResponse(writer)
+}
+
+// deleteFaultsHandler removes the fault of the method in the 'method' query param, or all of them if not provided
+func (c FaultsController) deleteFaultsHandler(writer http.ResponseWriter, request *http.Request) {
+	method := grpchandler.ResolveMethod(c.Service, getQueryParam(request, requestParamMethod))
+	log.WithFields(log.Fields{"method": method}).
+		Info("REST: received call to delete faults")
+
+	if method == emptyString {
+		c.FaultsStore.DeleteAll()
+	} else {
+		c.FaultsStore.Delete(method)
+	}
+	writeSuccessResponse(writer)
+}
+
+// setRandomSeedHandler seeds everything random in the stubs so that test runs are reproducible
+func (c FaultsController) setRandomSeedHandler(writer http.ResponseWriter, request *http.Request) {
+	seed := new(RandomSeed)
+	if err := readFromRequestBody(request, seed, "seed"); err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to set random seed failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"seed": seed.Seed}).
+		Info("REST: received call to set random seed")
+
+	stub.SetRandomSeed(seed.Seed)
+	writeSuccessResponse(writer)
+}
+
+func readFromRequestBody(request *http.Request, payload interface{}, name string) error {
+	bodyData, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorf("Unexpected error while reading %s from the request. Error %s", name, err.Error())
+		return fmt.Errorf("could not read %s in payload", name)
+	}
+	defer request.Body.Close()
+
+	unmarshalErr := json.Unmarshal(bodyData, payload)
+	if unmarshalErr != nil {
+		log.Errorf("Unexpected error while reading %s from the request. Error %s", name, unmarshalErr.Error())
+		return fmt.Errorf("could not read %s in payload", name)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package restcontrollers
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFaultsController_GetPath(t *testing.T) {
+	ctrl := FaultsController{}
+
+	assert.Equal(t, "/faults", ctrl.GetPath())
+}
+
+func TestFaultsController_GetHandlers(t *testing.T) {
+	ctrl := FaultsController{}
+
+	assert.Equal(t, 4, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "GetFaults"), http.MethodGet)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "SetFault"), http.MethodPut)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "DeleteFaults"), http.MethodDelete)
+	seedHandler := findHandler(ctrl.GetHandlers(), "SetRandomSeed")
+	assert.Equal(t, "/seed", seedHandler.Path)
+	assert.Equal(t, []string{http.MethodPut}, seedHandler.Methods)
+}
+
+func TestFaultsController_setFaultHandler(t *testing.T) {
+	faultsStore := stub.NewInMemoryFaultsStore()
+	ctrl := FaultsController{FaultsStore: faultsStore}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/faults", strings.NewReader(`{"fullMethod":"method1","fault":{"error":{"percentage":25,"code":14,"message":"unavailable"}}}`))
+	findHandler(ctrl.GetHandlers(), "SetFault").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, &stub.Fault{Error: &stub.ErrorFault{Percentage: 25, Code: 14, Message: "unavailable"}}, faultsStore.Get("method1"))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/faults", nil)
+	findHandler(ctrl.GetHandlers(), "GetFaults").Handler(response, request)
+	assert.Equal(t, `[{"fullMethod":"method1","fault":{"error":{"percentage":25,"code":14,"message":"unavailable"}}}]`, response.Body.String())
+}
+
+func TestFaultsController_setFaultHandler_Invalid(t *testing.T) {
+	ctrl := FaultsController{FaultsStore: stub.NewInMemoryFaultsStore()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/faults", strings.NewReader(`{"fullMethod":"method1","fault":{"abort":{"percentage":10,"type":"socket"}}}`))
+	findHandler(ctrl.GetHandlers(), "SetFault").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Fault abort: type can only be 'connection'.", response.Body.String())
+}
+
+func TestFaultsController_deleteFaultsHandler(t *testing.T) {
+	faultsStore := stub.NewInMemoryFaultsStore()
+	fault := &stub.Fault{Abort: &stub.AbortFault{Percentage: 10, Type: "connection"}}
+	faultsStore.Set("method1", fault)
+	faultsStore.Set("method2", fault)
+	ctrl := FaultsController{FaultsStore: faultsStore}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodDelete, "/faults?method=method1", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteFaults").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Nil(t, faultsStore.Get("method1"))
+	assert.NotNil(t, faultsStore.Get("method2"))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodDelete, "/faults", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteFaults").Handler(response, request)
+	assert.Empty(t, faultsStore.GetAll())
+}
+
+func TestFaultsController_setRandomSeedHandler(t *testing.T) {
+	ctrl := FaultsController{}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/faults/seed", strings.NewReader(`{"seed":42}`))
+	findHandler(ctrl.GetHandlers(), "SetRandomSeed").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+}
+
+func TestFaultsController_MethodAlias(t *testing.T) {
+	faultsStore := stub.NewInMemoryFaultsStore()
+	ctrl := FaultsController{FaultsStore: faultsStore, Service: aliasedMockService{}}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/faults", strings.NewReader(`{"fullMethod":"/pkg.Service/Method","fault":{"error":{"percentage":25,"code":14,"message":"unavailable"}}}`))
+	findHandler(ctrl.GetHandlers(), "SetFault").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.NotNil(t, faultsStore.Get("/pkg.Service/method"))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodDelete, "/faults?method=/pkg.Service/Method", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteFaults").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Nil(t, faultsStore.Get("/pkg.Service/method"))
+}
@@ -1,13 +1,27 @@
 package restcontrollers
 
 import (
+	"fmt"
 	"github.com/carvalhorr/protoc-gen-mock/stub"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 )
 
+const (
+	requestParamPartialMatch          = "partialMatch"
+	requestParamDropMetadata          = "dropMetadata"
+	requestParamKeepTransportMetadata = "keepTransportMetadata"
+)
+
 type RecordingsController struct {
 	RecordingsStore stub.RecordingsStore
+	Stubs           StubsController // validates, cleans and adds the stubs promoted like the stubs added with POST /stubs
+}
+
+// PromotionResult tells which of the recordings selected were added to the stubs
+type PromotionResult struct {
+	Promoted int      `json:"promoted"`
+	Skipped  []string `json:"skipped"`
 }
 
 func (c RecordingsController) GetHandlers() []RESTHandler {
@@ -18,6 +32,24 @@ func (c RecordingsController) GetHandlers() []RESTHandler {
 			Methods: []string{http.MethodGet},
 			Handler: c.getRecordingsHandler,
 		},
+		{
+			Name:    "DeleteRecordings",
+			Path:    "",
+			Methods: []string{http.MethodDelete},
+			Handler: c.deleteRecordingsHandler,
+		},
+		{
+			Name:    "PromoteRecordings",
+			Path:    "/promote",
+			Methods: []string{http.MethodPost},
+			Handler: c.promoteRecordingsHandler,
+		},
+		{
+			Name:    "ExportRecordings",
+			Path:    "/export",
+			Methods: []string{http.MethodGet},
+			Handler: c.exportRecordingsHandler,
+		},
 	}
 }
 
@@ -34,3 +66,65 @@ func (c RecordingsController) getRecordingsHandler(writer http.ResponseWriter, r
 		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
 	}
 }
+
+func (c RecordingsController) deleteRecordingsHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info("REST: received call to delete recordings")
+
+	c.RecordingsStore.DeleteAll()
+	writeSuccessResponse(writer)
+}
+
+// promoteRecordingsHandler adds the recordings selected in the body to the stubs. Without a body all of them are added.
+// Recordings whose request already has a stub, or that aren't valid stubs, are skipped.
+func (c RecordingsController) promoteRecordingsHandler(writer http.ResponseWriter, request *http.Request) {
+	options := new(stub.PromotionOptions)
+	if request.ContentLength != 0 {
+		if err := readFromRequestBody(request, options, "promotion options"); err != nil {
+			writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to promote recordings failed with error: %s", err.Error()))
+			return
+		}
+	}
+	log.WithFields(log.Fields{"options": toJSON(options)}).
+		Info("REST: received call to promote recordings")
+
+	stubs, err := stub.PromoteRecordings(c.RecordingsStore.GetAllStubs(), *options)
+	if err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to promote recordings failed with error: %s", err.Error()))
+		return
+	}
+	result := PromotionResult{Skipped: make([]string, 0)}
+	for _, s := range stubs {
+		if rejected := c.Stubs.checkNewStub(s); rejected != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s -> %s: %s", s.FullMethod, s.Request.String(), rejected.Error()))
+			continue
+		}
+		if addErr := c.Stubs.StubsStore.Add(s); addErr != nil {
+			result.Skipped = append(result.Skipped, addErr.Error())
+			continue
+		}
+		result.Promoted++
+	}
+	writeErr := writeResponse(writer, result)
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+// exportRecordingsHandler returns all the recordings as an array of stubs to download, which POST /stubs adds back.
+// The 'partialMatch', 'dropMetadata' and 'keepTransportMetadata' query params work like the promotion options of the same name.
+func (c RecordingsController) exportRecordingsHandler(writer http.ResponseWriter, request *http.Request) {
+	options := stub.PromotionOptions{
+		PartialMatch:          getQueryParam(request, requestParamPartialMatch) == "true",
+		DropMetadata:          request.URL.Query()[requestParamDropMetadata],
+		KeepTransportMetadata: getQueryParam(request, requestParamKeepTransportMetadata) == "true",
+	}
+	log.WithFields(log.Fields{"options": toJSON(options)}).
+		Info("REST: received call to export recordings")
+
+	stubs, _ := stub.PromoteRecordings(c.RecordingsStore.GetAllStubs(), options)
+	writer.Header().Set("Content-Disposition", `attachment; filename="stubs.json"`)
+	writeErr := writeResponse(writer, stubs)
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
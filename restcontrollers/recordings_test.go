@@ -0,0 +1,172 @@
+package restcontrollers
+
+import (
+	"encoding/json"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRecording(request, response string) *stub.Stub {
+	return &stub.Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &stub.StubRequest{Match: "exact", Content: stub.JsonString(request), Metadata: map[string][]string{"x-request-id": {"1"}}},
+		Response:   &stub.StubResponse{Type: "success", Content: stub.JsonString(response)},
+	}
+}
+
+func TestRecordingsController_GetHandlers(t *testing.T) {
+	ctrl := RecordingsController{}
+
+	assert.Equal(t, "/recordings", ctrl.GetPath())
+	assert.Equal(t, 4, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "GetRecordings"), http.MethodGet)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "DeleteRecordings"), http.MethodDelete)
+	assert.Equal(t, "/promote", findHandler(ctrl.GetHandlers(), "PromoteRecordings").Path)
+	assert.Equal(t, "/export", findHandler(ctrl.GetHandlers(), "ExportRecordings").Path)
+}
+
+func TestRecordingsController_promoteRecordingsHandler(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	stubsStore := stub.NewInMemoryStubsStore()
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hello a"}`))
+	recordingsStore.Add(newRecording(`{"name":"b"}`, `{"greeting":"hi b"}`))
+	ctrl := RecordingsController{RecordingsStore: recordingsStore, Stubs: newStructStubsController(stubsStore)}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/recordings/promote", strings.NewReader(`{"partialMatch":true,"dropMetadata":["x-request-id"]}`))
+	findHandler(ctrl.GetHandlers(), "PromoteRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	result := new(PromotionResult)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), result))
+	assert.Equal(t, 2, result.Promoted)
+	assert.Empty(t, result.Skipped)
+
+	stubs := stubsStore.GetAllStubs()
+	assert.Equal(t, 2, len(stubs))
+	assert.Equal(t, "partial", stubs[0].Request.Match)
+	assert.Nil(t, stubs[0].Request.Metadata)
+	assert.JSONEq(t, `{"name":"a"}`, string(stubs[0].Request.Content))
+	assert.JSONEq(t, `{"greeting":"hi a"}`, string(stubs[0].Response.Content))
+
+	// promoting the same recordings again skips the stubs already added
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/recordings/promote", strings.NewReader(`{"partialMatch":true,"dropMetadata":["x-request-id"]}`))
+	findHandler(ctrl.GetHandlers(), "PromoteRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	result = new(PromotionResult)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), result))
+	assert.Equal(t, 0, result.Promoted)
+	assert.Equal(t, 2, len(result.Skipped))
+	assert.True(t, strings.HasPrefix(result.Skipped[0], "method1 -> "))
+	assert.True(t, strings.HasSuffix(result.Skipped[0], ": Stub already exists"))
+}
+
+func TestRecordingsController_exportRecordingsHandler_SameRequest(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hello a"}`))
+	ctrl := RecordingsController{RecordingsStore: recordingsStore}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/recordings/export", nil)
+	findHandler(ctrl.GetHandlers(), "ExportRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	exported := make([]*stub.Stub, 0)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &exported))
+	assert.Equal(t, 1, len(exported))
+
+	stubsStore := stub.NewInMemoryStubsStore()
+	stubsCtrl := newStructStubsController(stubsStore)
+	imported := httptest.NewRecorder()
+	findHandler(stubsCtrl.GetHandlers(), "AddStub").Handler(imported, httptest.NewRequest(http.MethodPost, "/stubs", response.Body))
+	assert.Equal(t, 200, imported.Code)
+	stubs := stubsStore.GetAllStubs()
+	assert.Equal(t, 1, len(stubs))
+	assert.JSONEq(t, `{"greeting":"hi a"}`, string(stubs[0].Response.Content))
+}
+
+func TestRecordingsController_promoteRecordingsHandler_Invalid(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	stubsStore := stub.NewInMemoryStubsStore()
+	invalid := newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`)
+	invalid.Response.Type = "unknown"
+	recordingsStore.Add(invalid)
+	recordingsStore.Add(newRecording(`{"name":"b"}`, `{"greeting":"hi b"}`))
+	unsupported := newRecording(`{"name":"c"}`, `{"greeting":"hi c"}`)
+	unsupported.FullMethod = "method2"
+	recordingsStore.Add(unsupported)
+	ctrl := RecordingsController{RecordingsStore: recordingsStore, Stubs: newStructStubsController(stubsStore)}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/recordings/promote", nil)
+	findHandler(ctrl.GetHandlers(), "PromoteRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	result := new(PromotionResult)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), result))
+	assert.Equal(t, 1, result.Promoted)
+	assert.Equal(t, 2, len(result.Skipped))
+	assert.True(t, strings.HasPrefix(result.Skipped[0], "method1 -> "))
+	assert.True(t, strings.HasSuffix(result.Skipped[1], ": Method method2 is not supported"))
+	assert.Equal(t, 1, len(stubsStore.GetAllStubs()))
+}
+
+func TestRecordingsController_promoteRecordingsHandler_CopiesResponse(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	stubsStore := stub.NewInMemoryStubsStore()
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	ctrl := RecordingsController{RecordingsStore: recordingsStore, Stubs: newStructStubsController(stubsStore)}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/recordings/promote", nil)
+	findHandler(ctrl.GetHandlers(), "PromoteRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+
+	stubsStore.GetAllStubs()[0].Response.Content = `{"greeting":"changed"}`
+	assert.Equal(t, stub.JsonString(`{"greeting":"hi a"}`), recordingsStore.GetAllStubs()[0].Response.Content)
+}
+
+func TestRecordingsController_promoteRecordingsHandler_UnknownRecording(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	ctrl := RecordingsController{RecordingsStore: recordingsStore, Stubs: newStructStubsController(stub.NewInMemoryStubsStore())}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/recordings/promote", strings.NewReader(`{"recordings":[3]}`))
+	findHandler(ctrl.GetHandlers(), "PromoteRecordings").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "call to promote recordings failed with error: recording 3 does not exist", response.Body.String())
+}
+
+func TestRecordingsController_exportAndDeleteRecordingsHandler(t *testing.T) {
+	recordingsStore := stub.NewRecordingsStore()
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	recordingsStore.Add(newRecording(`{"name":"a"}`, `{"greeting":"hi a"}`))
+	ctrl := RecordingsController{RecordingsStore: recordingsStore}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/recordings/export?dropMetadata=x-request-id", nil)
+	findHandler(ctrl.GetHandlers(), "ExportRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, `attachment; filename="stubs.json"`, response.Header().Get("Content-Disposition"))
+	assert.Equal(t, `[{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"},"metadata":null},"response":{"type":"success","content":{"greeting":"hi a"},"error":null},"forward":null}]`, response.Body.String())
+
+	stubsStore := stub.NewInMemoryStubsStore()
+	stubsCtrl := newStructStubsController(stubsStore)
+	imported := httptest.NewRecorder()
+	findHandler(stubsCtrl.GetHandlers(), "AddStub").Handler(imported, httptest.NewRequest(http.MethodPost, "/stubs", response.Body))
+	assert.Equal(t, 200, imported.Code)
+	assert.Equal(t, 1, len(stubsStore.GetAllStubs()))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodDelete, "/recordings", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteRecordings").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Empty(t, recordingsStore.GetAllStubs())
+}
@@ -0,0 +1,133 @@
+package restcontrollers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	requestParamSince = "since"
+	requestParamUntil = "until"
+)
+
+type RequestsController struct {
+	RequestJournal stub.RequestJournal
+	Service        grpchandler.MockService
+}
+
+func (c RequestsController) GetHandlers() []RESTHandler {
+	return []RESTHandler{
+		{
+			Name:    "GetRequests",
+			Path:    "",
+			Methods: []string{http.MethodGet},
+			Handler: c.getRequestsHandler,
+		},
+		{
+			Name:    "ClearRequests",
+			Path:    "",
+			Methods: []string{http.MethodDelete},
+			Handler: c.clearRequestsHandler,
+		},
+		{
+			Name:    "VerifyRequests",
+			Path:    "/verify",
+			Methods: []string{http.MethodPost},
+			Handler: c.verifyRequestsHandler,
+		},
+	}
+}
+
+func (c RequestsController) GetPath() string {
+	return "/requests"
+}
+
+// getRequestsHandler lists the calls received in the order they arrived.
+// They can be filtered with the 'method', 'since' and 'until' query params. The times are in RFC3339 format.
+func (c RequestsController) getRequestsHandler(writer http.ResponseWriter, request *http.Request) {
+	log.WithFields(log.Fields{"query": request.URL.RawQuery}).
+		Info("REST: received call to get requests")
+
+	filter, err := readJournalFilter(request)
+	if err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to get requests failed with error: %s", err.Error()))
+		return
+	}
+	filter.FullMethod = grpchandler.ResolveMethod(c.Service, filter.FullMethod)
+
+	writeErr := writeResponse(writer, c.RequestJournal.Find(filter))
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+func (c RequestsController) clearRequestsHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info("REST: received call to clear requests")
+
+	c.RequestJournal.Clear()
+	writeSuccessResponse(writer)
+}
+
+// verifyRequestsHandler counts the calls received for a method with a request matching the one in the payload
+func (c RequestsController) verifyRequestsHandler(writer http.ResponseWriter, request *http.Request) {
+	verification, err := readVerificationFromRequestBody(request)
+	if err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to verify requests failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"verification": toJSON(verification)}).
+		Info("REST: received call to verify requests")
+
+	if isValid, errorMessages := verification.IsValid(); !isValid {
+		writeErrorResponse(writer, http.StatusBadRequest, strings.Join(errorMessages, " "))
+		return
+	}
+	verification.FullMethod = grpchandler.ResolveMethod(c.Service, verification.FullMethod)
+
+	result := stub.VerificationResult{Count: stub.CountCalls(c.RequestJournal, verification)}
+	writeErr := writeResponse(writer, result)
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+func readVerificationFromRequestBody(request *http.Request) (*stub.Verification, error) {
+	bodyData, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorf("Unexpected error while reading verification from the request. Error %s", err.Error())
+		return nil, fmt.Errorf("could not read verification in payload")
+	}
+	defer request.Body.Close()
+
+	verification := new(stub.Verification)
+	unmarshalErr := json.Unmarshal(bodyData, verification)
+	if unmarshalErr != nil {
+		log.Errorf("Unexpected error while reading verification from the request. Error %s", unmarshalErr.Error())
+		return nil, fmt.Errorf("could not read verification in payload")
+	}
+
+	return verification, nil
+}
+
+func readJournalFilter(request *http.Request) (stub.JournalFilter, error) {
+	filter := stub.JournalFilter{FullMethod: getQueryParam(request, requestParamMethod)}
+	var err error
+	if since := getQueryParam(request, requestParamSince); since != emptyString {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid '%s' time %s", requestParamSince, since)
+		}
+	}
+	if until := getQueryParam(request, requestParamUntil); until != emptyString {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("invalid '%s' time %s", requestParamUntil, until)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,117 @@
+package restcontrollers
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestsController_GetPath(t *testing.T) {
+	ctrl := RequestsController{}
+
+	assert.Equal(t, "/requests", ctrl.GetPath())
+}
+
+func TestRequestsController_GetHandlers(t *testing.T) {
+	ctrl := RequestsController{}
+
+	assert.Equal(t, 3, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "GetRequests"), http.MethodGet)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "ClearRequests"), http.MethodDelete)
+	verifyHandler := findHandler(ctrl.GetHandlers(), "VerifyRequests")
+	assert.Equal(t, []string{http.MethodPost}, verifyHandler.Methods)
+	assert.Equal(t, "/verify", verifyHandler.Path)
+}
+
+func newTestJournal() stub.RequestJournal {
+	journal := stub.NewInMemoryRequestJournal(10)
+	journal.Add(stub.JournalEntry{
+		FullMethod: "method1",
+		Request:    "{\"name\":\"a\"}",
+		Timestamp:  time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+	})
+	journal.Add(stub.JournalEntry{
+		FullMethod: "method2",
+		Request:    "{}",
+		Timestamp:  time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC),
+		Status:     &stub.ErrorResponse{Code: 5, Message: "no response found for method2"},
+	})
+	return journal
+}
+
+func TestRequestsController_getRequestsHandler(t *testing.T) {
+	ctrl := RequestsController{RequestJournal: newTestJournal()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/requests?method=method1", nil)
+	findHandler(ctrl.GetHandlers(), "GetRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "[{\"fullMethod\":\"method1\",\"request\":{\"name\":\"a\"},\"metadata\":null,\"peer\":\"\",\"timestamp\":\"2021-01-01T10:00:00Z\",\"stub\":null}]", response.Body.String())
+}
+
+func TestRequestsController_getRequestsHandler_TimeRange(t *testing.T) {
+	ctrl := RequestsController{RequestJournal: newTestJournal()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/requests?since=2021-01-01T10:30:00Z&until=2021-01-01T12:00:00Z", nil)
+	findHandler(ctrl.GetHandlers(), "GetRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "[{\"fullMethod\":\"method2\",\"request\":{},\"metadata\":null,\"peer\":\"\",\"timestamp\":\"2021-01-01T11:00:00Z\",\"stub\":null,\"status\":{\"code\":5,\"message\":\"no response found for method2\",\"details\":null}}]", response.Body.String())
+}
+
+func TestRequestsController_getRequestsHandler_InvalidTime(t *testing.T) {
+	ctrl := RequestsController{RequestJournal: newTestJournal()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/requests?since=yesterday", nil)
+	findHandler(ctrl.GetHandlers(), "GetRequests").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "call to get requests failed with error: invalid 'since' time yesterday", response.Body.String())
+}
+
+func TestRequestsController_clearRequestsHandler(t *testing.T) {
+	journal := newTestJournal()
+	ctrl := RequestsController{RequestJournal: journal}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodDelete, "/requests", nil)
+	findHandler(ctrl.GetHandlers(), "ClearRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 0, len(journal.Find(stub.JournalFilter{})))
+}
+
+func TestRequestsController_verifyRequestsHandler(t *testing.T) {
+	ctrl := RequestsController{RequestJournal: newTestJournal()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/requests/verify", strings.NewReader(`{"fullMethod":"method1","request":{"match":"partial","content":{"name":"a"}}}`))
+	findHandler(ctrl.GetHandlers(), "VerifyRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "{\"count\":1}", response.Body.String())
+}
+
+func TestRequestsController_verifyRequestsHandler_InvalidVerification(t *testing.T) {
+	ctrl := RequestsController{RequestJournal: newTestJournal()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/requests/verify", strings.NewReader(`{"request":{"match":"exact"}}`))
+	findHandler(ctrl.GetHandlers(), "VerifyRequests").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Full method name can't be empty.", response.Body.String())
+}
+
+func TestRequestsController_MethodAlias(t *testing.T) {
+	journal := stub.NewInMemoryRequestJournal(10)
+	journal.Add(stub.JournalEntry{FullMethod: "/pkg.Service/method", Request: "{}"})
+	ctrl := RequestsController{RequestJournal: journal, Service: aliasedMockService{}}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/requests?method=/pkg.Service/Method", nil)
+	findHandler(ctrl.GetHandlers(), "GetRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"fullMethod\":\"/pkg.Service/method\"")
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/requests/verify", strings.NewReader(`{"fullMethod":"/pkg.Service/Method"}`))
+	findHandler(ctrl.GetHandlers(), "VerifyRequests").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "{\"count\":1}", response.Body.String())
+}
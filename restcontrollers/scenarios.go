@@ -0,0 +1,122 @@
+package restcontrollers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+const requestParamName = "name"
+
+type ScenariosController struct {
+	StubsStore     stub.StubsStore
+	ScenariosStore stub.ScenariosStore
+}
+
+func (c ScenariosController) GetHandlers() []RESTHandler {
+	return []RESTHandler{
+		{
+			Name:    "GetScenarios",
+			Path:    "",
+			Methods: []string{http.MethodGet},
+			Handler: c.getScenariosHandler,
+		},
+		{
+			Name:    "SetScenarioState",
+			Path:    "",
+			Methods: []string{http.MethodPut},
+			Handler: c.setScenarioStateHandler,
+		},
+		{
+			Name:    "ResetScenarios",
+			Path:    "",
+			Methods: []string{http.MethodDelete},
+			Handler: c.resetScenariosHandler,
+		},
+	}
+}
+
+func (c ScenariosController) GetPath() string {
+	return "/scenarios"
+}
+
+// getScenariosHandler lists the scenarios used by the stubs and the ones whose state was set, with their current state
+func (c ScenariosController) getScenariosHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info("REST: received call to get scenarios")
+
+	names := make(map[string]bool, 0)
+	for _, s := range c.StubsStore.GetAllStubs() {
+		if s.Scenario != emptyString {
+			names[s.Scenario] = true
+		}
+	}
+	for _, scenario := range c.ScenariosStore.GetAll() {
+		names[scenario.Name] = true
+	}
+	scenarios := make([]stub.Scenario, 0)
+	for name := range names {
+		scenarios = append(scenarios, stub.Scenario{Name: name, State: c.ScenariosStore.GetState(name)})
+	}
+	sort.Slice(scenarios, func(i, j int) bool {
+		return scenarios[i].Name < scenarios[j].Name
+	})
+
+	writeErr := writeResponse(writer, scenarios)
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+func (c ScenariosController) setScenarioStateHandler(writer http.ResponseWriter, request *http.Request) {
+	scenario, err := readScenarioFromRequestBody(request)
+	if err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to set scenario state failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"scenario": toJSON(scenario)}).
+		Info("REST: received call to set scenario state")
+
+	if scenario.Name == emptyString || scenario.State == emptyString {
+		writeErrorResponse(writer, http.StatusBadRequest, "Scenario name and state can't be empty")
+		return
+	}
+
+	c.ScenariosStore.SetState(scenario.Name, scenario.State)
+	writeSuccessResponse(writer)
+}
+
+// resetScenariosHandler moves the scenario in the 'name' query param, or all of them if not provided, back to the initial state
+func (c ScenariosController) resetScenariosHandler(writer http.ResponseWriter, request *http.Request) {
+	name := getQueryParam(request, requestParamName)
+	log.WithFields(log.Fields{"name": name}).
+		Info("REST: received call to reset scenarios")
+
+	if name == emptyString {
+		c.ScenariosStore.ResetAll()
+	} else {
+		c.ScenariosStore.Reset(name)
+	}
+	writeSuccessResponse(writer)
+}
+
+func readScenarioFromRequestBody(request *http.Request) (*stub.Scenario, error) {
+	bodyData, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorf("Unexpected error while reading scenario from the request. Error %s", err.Error())
+		return nil, fmt.Errorf("could not read scenario in payload")
+	}
+	defer request.Body.Close()
+
+	scenario := new(stub.Scenario)
+	unmarshalErr := json.Unmarshal(bodyData, scenario)
+	if unmarshalErr != nil {
+		log.Errorf("Unexpected error while reading scenario from the request. Error %s", unmarshalErr.Error())
+		return nil, fmt.Errorf("could not read scenario in payload")
+	}
+
+	return scenario, nil
+}
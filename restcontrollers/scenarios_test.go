@@ -0,0 +1,84 @@
+package restcontrollers
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScenariosController_GetPath(t *testing.T) {
+	ctrl := ScenariosController{}
+
+	assert.Equal(t, "/scenarios", ctrl.GetPath())
+}
+
+func TestScenariosController_GetHandlers(t *testing.T) {
+	ctrl := ScenariosController{}
+
+	assert.Equal(t, 3, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "GetScenarios"), http.MethodGet)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "SetScenarioState"), http.MethodPut)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "ResetScenarios"), http.MethodDelete)
+}
+
+func TestScenariosController_getScenariosHandler(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	stubsStore.Add(&stub.Stub{
+		FullMethod: "method1",
+		Request:    &stub.StubRequest{Match: "exact", Content: "{}"},
+		Scenario:   "order",
+		NewState:   "CANCELLED",
+	})
+	scenariosStore := stub.NewInMemoryScenariosStore()
+	scenariosStore.SetState("payment", "PAID")
+	ctrl := ScenariosController{
+		StubsStore:     stubsStore,
+		ScenariosStore: scenariosStore,
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/scenarios", nil)
+	findHandler(ctrl.GetHandlers(), "GetScenarios").Handler(response, request)
+	assert.Equal(t, "[{\"name\":\"order\",\"state\":\"Started\"},{\"name\":\"payment\",\"state\":\"PAID\"}]", response.Body.String())
+	assert.Equal(t, 200, response.Code)
+}
+
+func TestScenariosController_setScenarioStateHandler(t *testing.T) {
+	scenariosStore := stub.NewInMemoryScenariosStore()
+	ctrl := ScenariosController{
+		ScenariosStore: scenariosStore,
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/scenarios", strings.NewReader(`{"name":"order","state":"CANCELLED"}`))
+	findHandler(ctrl.GetHandlers(), "SetScenarioState").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "CANCELLED", scenariosStore.GetState("order"))
+}
+
+func TestScenariosController_setScenarioStateHandler_MissingState(t *testing.T) {
+	ctrl := ScenariosController{
+		ScenariosStore: stub.NewInMemoryScenariosStore(),
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/scenarios", strings.NewReader(`{"name":"order"}`))
+	findHandler(ctrl.GetHandlers(), "SetScenarioState").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Scenario name and state can't be empty", response.Body.String())
+}
+
+func TestScenariosController_resetScenariosHandler(t *testing.T) {
+	scenariosStore := stub.NewInMemoryScenariosStore()
+	scenariosStore.SetState("order", "CANCELLED")
+	scenariosStore.SetState("payment", "PAID")
+	ctrl := ScenariosController{
+		ScenariosStore: scenariosStore,
+	}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodDelete, "/scenarios?name=order", nil)
+	findHandler(ctrl.GetHandlers(), "ResetScenarios").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, stub.ScenarioStarted, scenariosStore.GetState("order"))
+	assert.Equal(t, "PAID", scenariosStore.GetState("payment"))
+}
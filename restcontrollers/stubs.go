@@ -1,6 +1,7 @@
 package restcontrollers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
@@ -12,6 +13,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -63,7 +65,7 @@ func (c StubsController) GetPath() string {
 func (c StubsController) getStubsHandler(writer http.ResponseWriter, request *http.Request) {
 	log.Info("REST: received call to get stubs")
 
-	method := getQueryParam(request, requestParamMethod)
+	method := c.resolveMethod(getQueryParam(request, requestParamMethod))
 	if method != emptyString && !c.isMethodSupported(method) {
 		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("Unsupported method: %s", method))
 		return
@@ -76,53 +78,138 @@ func (c StubsController) getStubsHandler(writer http.ResponseWriter, request *ht
 	}
 }
 
+// addStubsHandler adds the stub in the body, or all the stubs of an array like the one exported from the recordings.
+// The stubs of an array are only added when all of them are valid and their requests are different.
 func (c StubsController) addStubsHandler(writer http.ResponseWriter, request *http.Request) {
-	s, err := readStubFromRequestBody(request)
+	stubs, err := readStubsFromRequestBody(request)
 	if err != nil {
 		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to add stubs failed with error: %s", err.Error()))
 		return
 	}
-	log.WithFields(log.Fields{"stub": toJSON(s)}).
-		Info("REST: received call to add stub")
+	for _, s := range stubs {
+		s.FullMethod = c.resolveMethod(s.FullMethod)
+	}
+	log.WithFields(log.Fields{"stubs": toJSON(stubs)}).
+		Info("REST: received call to add stubs")
+
+	keys := make(map[string]bool, len(stubs))
+	for i, s := range stubs {
+		rejected := c.checkNewStub(s)
+		key := s.FullMethod + " " + s.Key()
+		if rejected == nil && keys[key] {
+			rejected = &rejectedStub{code: http.StatusBadRequest, message: "Stub has the same request as a previous stub"}
+		}
+		if rejected != nil {
+			if len(stubs) > 1 {
+				rejected = rejected.ofStub(i)
+			}
+			c.writeRejectedStub(writer, s, rejected)
+			return
+		}
+		keys[key] = true
+	}
 
-	if !c.isMethodSupported(s.FullMethod) {
-		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("Method %s is not supported", s.FullMethod))
-		return
+	for _, s := range stubs {
+		addErr := c.StubsStore.Add(s)
+		if addErr != nil {
+			log.Errorf("Failed to add stub %s -> %s. Error %s", s.FullMethod, s.Request.String(), addErr.Error())
+			writeErrorResponse(writer, http.StatusInternalServerError, "Failed to add stub.")
+			return
+		}
 	}
+	writeSuccessResponse(writer)
+}
 
-	if !c.isValid(writer, s) {
-		return
+// rejectedStub tells why a stub can't be added or updated and the status to reply with
+type rejectedStub struct {
+	code    int
+	message string
+	errors  []string // errors of the validation of the stub, replied together with an example of its method
+}
+
+func (r *rejectedStub) Error() string {
+	if len(r.errors) > 0 {
+		return strings.Join(r.errors, " ")
+	}
+	return r.message
+}
+
+// ofStub tells the position of the stub rejected in the array added
+func (r *rejectedStub) ofStub(i int) *rejectedStub {
+	errors := make([]string, 0, len(r.errors))
+	for _, err := range r.errors {
+		errors = append(errors, fmt.Sprintf("Stub %d: %s", i, err))
 	}
+	return &rejectedStub{code: r.code, message: fmt.Sprintf("Stub %d: %s", i, r.message), errors: errors}
+}
 
+// checkNewStub checks a stub can be added to the store, cleaning its contents
+func (c StubsController) checkNewStub(s *stub.Stub) *rejectedStub {
+	if !c.isMethodSupported(s.FullMethod) {
+		return &rejectedStub{code: http.StatusBadRequest, message: fmt.Sprintf("Method %s is not supported", s.FullMethod)}
+	}
+	if rejected := c.checkStub(s); rejected != nil {
+		return rejected
+	}
 	if c.StubsStore.Exists(s) {
-		writeErrorResponse(writer, http.StatusConflict, "Stub already exists")
-		return
+		return &rejectedStub{code: http.StatusConflict, message: "Stub already exists"}
 	}
+	return nil
+}
 
-	addErr := c.StubsStore.Add(s)
-	if addErr != nil {
-		log.Errorf("Failed to add stub %s -> %s. Error %s", s.FullMethod, s.Request.String(), addErr.Error())
-		writeErrorResponse(writer, http.StatusInternalServerError, "Failed to add stub.")
+func (c StubsController) writeRejectedStub(writer http.ResponseWriter, s *stub.Stub, rejected *rejectedStub) {
+	if len(rejected.errors) == 0 {
+		writeErrorResponse(writer, rejected.code, rejected.message)
 		return
 	}
-	writeSuccessResponse(writer)
+	invalidStubMessage := stub.InvalidStubResponse{
+		Errors:  rejected.errors,
+		Example: *c.findExampleForMethod(s.FullMethod),
+	}
+	writeResponseWithCode(writer, invalidStubMessage, rejected.code)
 }
 
 // 1. Make sure the request and response can be marshalled to the respective proto.Messages by unmarshalling it to the respective type
 // 2. Marshal it back to JSON to remove extra spaces or formatting so that we can use this cleaned up JSON for comparison to check if the stub already exists
+// cleanRequestResponse normalises the contents to the JSON the proto messages are marshalled to.
+// Request contents using operators and response templates are kept as they are since they aren't valid messages.
 func (c StubsController) cleanRequestResponse(s *stub.Stub) error {
-	marshaledRequest, errReqClean := cleanJson(s.Request.Content, c.Service.GetRequestInstance(s.FullMethod))
-	if errReqClean != nil {
-		return errReqClean
+	if (s.Request.Content != "" || (!s.Request.IsStream() && s.Request.Match != "jsonpath" && s.Request.Match != "cel")) && !s.Request.Content.HasOperators() {
+		marshaledRequest, errReqClean := cleanJson(s.Request.Content, c.Service.GetRequestInstance(s.FullMethod))
+		if errReqClean != nil {
+			return errReqClean
+		}
+		s.Request.Content = marshaledRequest
 	}
-	s.Request.Content = marshaledRequest
-	if s.Type == "mock" {
+	for i, content := range s.Request.Stream {
+		if content.HasOperators() {
+			continue
+		}
+		marshaledRequest, errReqClean := cleanJson(content, c.Service.GetRequestInstance(s.FullMethod))
+		if errReqClean != nil {
+			return errReqClean
+		}
+		s.Request.Stream[i] = marshaledRequest
+	}
+	if s.Type == "mock" && !s.Response.Template {
 		marshalledResponse, errRespClean := cleanJson(s.Response.Content, c.Service.GetResponseInstance(s.FullMethod))
 		if errRespClean != nil {
 			return errRespClean
 		}
 		s.Response.Content = marshalledResponse
 	}
+	if s.Type == "mock" && s.Response.Sequence != nil {
+		for _, sequenceResponse := range s.Response.Sequence.Responses {
+			if sequenceResponse.Type != "success" || sequenceResponse.Template || s.Response.Template {
+				continue
+			}
+			marshalledResponse, errRespClean := cleanJson(sequenceResponse.Content, c.Service.GetResponseInstance(s.FullMethod))
+			if errRespClean != nil {
+				return errRespClean
+			}
+			sequenceResponse.Content = marshalledResponse
+		}
+	}
 	return nil
 }
 
@@ -147,6 +234,7 @@ func (c StubsController) updateStubsHandler(writer http.ResponseWriter, request
 		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to update stub failed with error: %s", err.Error()))
 		return
 	}
+	s.FullMethod = c.resolveMethod(s.FullMethod)
 	log.WithFields(log.Fields{"stub": toJSON(s)}).
 		Info("REST: received call to update stub")
 
@@ -174,7 +262,7 @@ func (c StubsController) updateStubsHandler(writer http.ResponseWriter, request
 }
 
 func (c StubsController) deleteStubsHandler(writer http.ResponseWriter, request *http.Request) {
-	method := getQueryParam(request, requestParamMethod)
+	method := c.resolveMethod(getQueryParam(request, requestParamMethod))
 	if method != emptyString && !c.isMethodSupported(method) {
 		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("Can't delete stubs. Unsupported method: %s", method))
 	}
@@ -184,6 +272,9 @@ func (c StubsController) deleteStubsHandler(writer http.ResponseWriter, request
 		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to delete stub failed with error: %s", err.Error()))
 		return
 	}
+	if stub != nil {
+		stub.FullMethod = c.resolveMethod(stub.FullMethod)
+	}
 	log.WithFields(log.Fields{"stub": toJSON(stub), "method": method}).
 		Info("REST: received call to delete stubs")
 
@@ -221,6 +312,10 @@ func (c StubsController) isMethodSupported(method string) bool {
 	return false
 }
 
+func (c StubsController) resolveMethod(method string) string {
+	return grpchandler.ResolveMethod(c.Service, method)
+}
+
 func (c StubsController) getStubsFromStore(method string) []*stub.Stub {
 	if method == emptyString {
 		return c.StubsStore.GetAllStubs()
@@ -236,6 +331,40 @@ func (c StubsController) isStubValid(s *stub.Stub) (isValid bool, errorMessages
 	return true, nil
 }
 
+// readStubsFromRequestBody reads a single stub or an array of stubs
+func readStubsFromRequestBody(request *http.Request) ([]*stub.Stub, error) {
+	bodyData, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorf("Unexpected error while reading stubs from the request. Error %s", err.Error())
+		return nil, fmt.Errorf("could not read stubs in payload")
+	}
+	defer request.Body.Close()
+
+	trimmed := bytes.TrimSpace(bodyData)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		s := new(stub.Stub)
+		if unmarshalErr := json.Unmarshal(bodyData, s); unmarshalErr != nil {
+			log.Errorf("Unexpected error while reading stub from the request. Error %s", unmarshalErr.Error())
+			return nil, fmt.Errorf("could not read stubs in payload")
+		}
+		return []*stub.Stub{s}, nil
+	}
+	stubs := make([]*stub.Stub, 0)
+	if unmarshalErr := json.Unmarshal(bodyData, &stubs); unmarshalErr != nil {
+		log.Errorf("Unexpected error while reading stubs from the request. Error %s", unmarshalErr.Error())
+		return nil, fmt.Errorf("could not read stubs in payload")
+	}
+	if len(stubs) == 0 {
+		return nil, fmt.Errorf("no stubs in payload")
+	}
+	for _, s := range stubs {
+		if s == nil {
+			return nil, fmt.Errorf("could not read stubs in payload")
+		}
+	}
+	return stubs, nil
+}
+
 func readStubFromRequestBody(request *http.Request) (*stub.Stub, error) {
 	bodyData, err := ioutil.ReadAll(request.Body)
 	if err != nil {
@@ -259,30 +388,33 @@ func readStubFromRequestBody(request *http.Request) (*stub.Stub, error) {
 }
 
 func toJSON(p interface{}) string {
-	str, _ := json.Marshal(p)
+	str, _ := json.Marshal(redactSecrets(p))
 	return string(str)
 }
 
 func (c StubsController) isValid(writer http.ResponseWriter, s *stub.Stub) bool {
+	if rejected := c.checkStub(s); rejected != nil {
+		c.writeRejectedStub(writer, s, rejected)
+		return false
+	}
+	return true
+}
+
+// checkStub validates the stub and cleans its contents
+func (c StubsController) checkStub(s *stub.Stub) *rejectedStub {
 	isValid, errorMessages := c.isStubValid(s)
 	if !isValid {
-		invalidStubMessage := stub.InvalidStubResponse{
-			Errors:  errorMessages,
-			Example: *c.findExampleForMethod(s.FullMethod),
-		}
-		writeResponseWithCode(writer, invalidStubMessage, http.StatusBadRequest)
-		return false
+		return &rejectedStub{code: http.StatusBadRequest, errors: errorMessages}
 	}
 
 	errCleaning := c.cleanRequestResponse(s)
 	if errCleaning != nil {
 		log.Errorf("Error validating request / response", errCleaning)
-		writeErrorResponse(writer, http.StatusInternalServerError, "Failed to update stub.")
-		return false
+		return &rejectedStub{code: http.StatusInternalServerError, message: "Failed to update stub."}
 	}
 
-	if s.Type != "mock" {
-		return true
+	if s.Type != "mock" || (s.Response.Type != "success" && s.Response.Type != "error") || s.Response.Template {
+		return nil // templates can only be turned into a response once rendered with a call
 	}
 	instance, createResponseErr := stub.GetResponse(s, string(s.Request.Content), c.Service.GetResponseInstance(s.FullMethod))
 	fmt.Println(instance, createResponseErr)
@@ -290,18 +422,16 @@ func (c StubsController) isValid(writer http.ResponseWriter, s *stub.Stub) bool
 	case "success":
 		if createResponseErr != nil {
 			log.Errorf("Error validating creation of response instance: %s", createResponseErr)
-			writeErrorResponse(writer, http.StatusBadRequest, "Error validating creation of response instance.")
-			return false
+			return &rejectedStub{code: http.StatusBadRequest, message: "Error validating creation of response instance."}
 		}
 	case "error":
 		st := status.Convert(createResponseErr)
 		fmt.Println(st.Code(), st.Message(), codes.Code(s.Response.Error.Code), s.Response.Error.Message)
 		if instance != nil || st.Code() != codes.Code(s.Response.Error.Code) || st.Message() != s.Response.Error.Message {
 			log.Errorf("Error validating creation of response instance: %s", createResponseErr)
-			writeErrorResponse(writer, http.StatusBadRequest, "Error validating creation of response instance.")
-			return false
+			return &rejectedStub{code: http.StatusBadRequest, message: "Error validating creation of response instance."}
 		}
 	}
 
-	return true
+	return nil
 }
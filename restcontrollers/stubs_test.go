@@ -1,8 +1,15 @@
 package restcontrollers
 
 import (
+	"encoding/json"
+	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	"github.com/golang/protobuf/proto"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -38,3 +45,166 @@ func findHandler(handlers []RESTHandler, name string) *RESTHandler {
 	}
 	return nil
 }
+
+// structMockService is a mock service whose method1 takes and returns a google.protobuf.Struct
+type structMockService struct {
+	grpchandler.MockService
+}
+
+func (s structMockService) GetSupportedMethods() []string {
+	return []string{"method1"}
+}
+
+func (s structMockService) GetRequestInstance(methodName string) proto.Message {
+	return new(structpb.Struct)
+}
+
+func (s structMockService) GetResponseInstance(methodName string) proto.Message {
+	return new(structpb.Struct)
+}
+
+func (s structMockService) GetStubsValidator() stub.StubsValidator {
+	return structStubsValidator{}
+}
+
+type structStubsValidator struct{}
+
+func (v structStubsValidator) IsValid(s *stub.Stub) (bool, []string) {
+	return s.IsValid()
+}
+
+func newStructStubsController(stubsStore stub.StubsStore) StubsController {
+	return StubsController{
+		StubsStore:   stubsStore,
+		StubExamples: []stub.Stub{{FullMethod: "method1", Type: "mock", Request: &stub.StubRequest{Match: "exact", Content: `{}`}, Response: &stub.StubResponse{Type: "success", Content: `{}`}}},
+		Service:      structMockService{},
+	}
+}
+
+// aliasedMockService is a mock service that accepts /pkg.Service/Method, the deprecated name of /pkg.Service/method
+type aliasedMockService struct {
+	grpchandler.MockService
+}
+
+func (s aliasedMockService) GetMethodAliases() map[string]string {
+	return map[string]string{"/pkg.Service/Method": "/pkg.Service/method"}
+}
+
+func TestStubsController_resolveMethod(t *testing.T) {
+	ctrl := StubsController{Service: aliasedMockService{}}
+
+	assert.Equal(t, "/pkg.Service/method", ctrl.resolveMethod("/pkg.Service/Method"))
+	assert.Equal(t, "/pkg.Service/method", ctrl.resolveMethod("/pkg.Service/method"))
+	assert.Equal(t, "/pkg.Service/Other", ctrl.resolveMethod("/pkg.Service/Other"))
+	assert.Equal(t, "", ctrl.resolveMethod(""))
+}
+
+func TestStubsController_resolveMethod_NoAliases(t *testing.T) {
+	ctrl := StubsController{}
+
+	assert.Equal(t, "/pkg.Service/Method", ctrl.resolveMethod("/pkg.Service/Method"))
+}
+
+func TestStubsController_addStubsHandler(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	ctrl := newStructStubsController(stubsStore)
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name" : "a"}},"response":{"type":"success","content":{"greeting":"hi a"}}}`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 1, len(stubsStore.GetAllStubs()))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"}},"response":{"type":"success","content":{"greeting":"hi a"}}}`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 409, response.Code)
+	assert.Equal(t, "Stub already exists", response.Body.String())
+}
+
+func TestStubsController_addStubsHandler_Array(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	ctrl := newStructStubsController(stubsStore)
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`[
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"}},"response":{"type":"success","content":{"greeting":"hi a"}}},
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"b"}},"response":{"type":"success","content":{"greeting":"hi b"}}}
+	]`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 2, len(stubsStore.GetAllStubs()))
+}
+
+func TestStubsController_addStubsHandler_ArrayRejected(t *testing.T) {
+	stubsStore := stub.NewInMemoryStubsStore()
+	ctrl := newStructStubsController(stubsStore)
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`[
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"}},"response":{"type":"success","content":{"greeting":"hi a"}}},
+		{"fullMethod":"method2","type":"mock","request":{"match":"exact","content":{"name":"b"}},"response":{"type":"success","content":{"greeting":"hi b"}}}
+	]`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Stub 1: Method method2 is not supported", response.Body.String())
+	assert.Empty(t, stubsStore.GetAllStubs())
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`[
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"}},"response":{"type":"success","content":{"greeting":"hi a"}}},
+		{"fullMethod":"method1","type":"mock","request":{"match":"fuzzy","content":{"name":"b"}},"response":{"type":"success","content":{"greeting":"hi b"}}}
+	]`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	invalid := new(stub.InvalidStubResponse)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), invalid))
+	assert.NotEmpty(t, invalid.Errors)
+	for _, message := range invalid.Errors {
+		assert.True(t, strings.HasPrefix(message, "Stub 1: "), message)
+	}
+	assert.Empty(t, stubsStore.GetAllStubs())
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`[
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name":"a"}},"response":{"type":"success","content":{"greeting":"hi a"}}},
+		{"fullMethod":"method1","type":"mock","request":{"match":"exact","content":{"name" : "a"}},"response":{"type":"success","content":{"greeting":"hello a"}}}
+	]`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Stub 1: Stub has the same request as a previous stub", response.Body.String())
+	assert.Empty(t, stubsStore.GetAllStubs())
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`[]`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "call to add stubs failed with error: no stubs in payload", response.Body.String())
+}
+
+func TestStubsController_RedactsBearerToken(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	stubsStore := stub.NewInMemoryStubsStore()
+	ctrl := newStructStubsController(stubsStore)
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/stubs", strings.NewReader(`{"fullMethod":"method1","type":"forward","request":{"match":"exact","content":{}},"forward":{"serverAddress":"localhost:1","credentials":{"bearerToken":"secret"}}}`))
+	findHandler(ctrl.GetHandlers(), "AddStub").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "secret", stubsStore.GetAllStubs()[0].Forward.Credentials.BearerToken)
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/stubs", nil)
+	findHandler(ctrl.GetHandlers(), "GetStubs").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), `"credentials":{"bearerToken":"[REDACTED]"}`)
+	assert.NotContains(t, response.Body.String(), "secret")
+	assert.Contains(t, hook.AllEntries()[0].Data["stubs"], `"bearerToken":"[REDACTED]"`)
+	for _, entry := range hook.AllEntries() {
+		for _, field := range entry.Data {
+			assert.NotContains(t, field, "secret")
+		}
+	}
+	assert.Equal(t, "secret", stubsStore.GetAllStubs()[0].Forward.Credentials.BearerToken)
+}
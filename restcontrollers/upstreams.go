@@ -0,0 +1,90 @@
+package restcontrollers
+
+import (
+	"fmt"
+	"github.com/carvalhorr/protoc-gen-mock/grpchandler"
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const requestParamTarget = "target"
+
+type UpstreamsController struct {
+	UpstreamsStore stub.UpstreamsStore
+	Service        grpchandler.MockService
+}
+
+func (c UpstreamsController) GetHandlers() []RESTHandler {
+	return []RESTHandler{
+		{
+			Name:    "GetUpstreams",
+			Path:    "",
+			Methods: []string{http.MethodGet},
+			Handler: c.getUpstreamsHandler,
+		},
+		{
+			Name:    "SetUpstream",
+			Path:    "",
+			Methods: []string{http.MethodPut},
+			Handler: c.setUpstreamHandler,
+		},
+		{
+			Name:    "DeleteUpstreams",
+			Path:    "",
+			Methods: []string{http.MethodDelete},
+			Handler: c.deleteUpstreamsHandler,
+		},
+	}
+}
+
+func (c UpstreamsController) GetPath() string {
+	return "/upstreams"
+}
+
+func (c UpstreamsController) getUpstreamsHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info("REST: received call to get upstreams")
+
+	writeErr := writeResponse(writer, c.UpstreamsStore.GetAll())
+	if writeErr != nil {
+		writeErrorResponse(writer, http.StatusInternalServerError, writeErr.Error())
+	}
+}
+
+// setUpstreamHandler sets the upstream of a target, replacing the previous one
+func (c UpstreamsController) setUpstreamHandler(writer http.ResponseWriter, request *http.Request) {
+	upstream := new(stub.Upstream)
+	if err := readFromRequestBody(request, upstream, "upstream"); err != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("call to set upstream failed with error: %s", err.Error()))
+		return
+	}
+	log.WithFields(log.Fields{"upstream": toJSON(upstream)}).
+		Info("REST: received call to set upstream")
+
+	if isValid, errorMessages := upstream.IsValid(); !isValid {
+		writeErrorResponse(writer, http.StatusBadRequest, strings.Join(errorMessages, " "))
+		return
+	}
+	upstream.Target = grpchandler.ResolveMethod(c.Service, upstream.Target)
+
+	c.UpstreamsStore.Set(upstream.Target, upstream.Forward)
+	writeSuccessResponse(writer)
+}
+
+// deleteUpstreamsHandler removes the upstream of the target in the 'target' query param, or all of them if not provided.
+// An empty target removes the upstream of all the calls.
+func (c UpstreamsController) deleteUpstreamsHandler(writer http.ResponseWriter, request *http.Request) {
+	targets, ok := request.URL.Query()[requestParamTarget]
+	log.WithFields(log.Fields{"target": targets}).
+		Info("REST: received call to delete upstreams")
+
+	if !ok {
+		c.UpstreamsStore.DeleteAll()
+	} else {
+		for _, target := range targets {
+			c.UpstreamsStore.Delete(grpchandler.ResolveMethod(c.Service, target))
+		}
+	}
+	writeSuccessResponse(writer)
+}
@@ -0,0 +1,106 @@
+package restcontrollers
+
+import (
+	"github.com/carvalhorr/protoc-gen-mock/stub"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpstreamsController_GetHandlers(t *testing.T) {
+	ctrl := UpstreamsController{}
+
+	assert.Equal(t, "/upstreams", ctrl.GetPath())
+	assert.Equal(t, 3, len(ctrl.GetHandlers()))
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "GetUpstreams"), http.MethodGet)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "SetUpstream"), http.MethodPut)
+	validateHandler(t, findHandler(ctrl.GetHandlers(), "DeleteUpstreams"), http.MethodDelete)
+}
+
+func TestUpstreamsController_setUpstreamHandler(t *testing.T) {
+	upstreamsStore := stub.NewInMemoryUpstreamsStore()
+	ctrl := UpstreamsController{UpstreamsStore: upstreamsStore}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/upstreams", strings.NewReader(`{"target":"pkg.Service","forward":{"serverAddress":"localhost:1","record":true}}`))
+	findHandler(ctrl.GetHandlers(), "SetUpstream").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, &stub.StubForward{ServerAddress: "localhost:1", Record: true}, upstreamsStore.Resolve("/pkg.Service/Method"))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/upstreams", nil)
+	findHandler(ctrl.GetHandlers(), "GetUpstreams").Handler(response, request)
+	assert.Equal(t, `[{"target":"pkg.Service","forward":{"serverAddress":"localhost:1","record":true}}]`, response.Body.String())
+}
+
+func TestUpstreamsController_setUpstreamHandler_Invalid(t *testing.T) {
+	ctrl := UpstreamsController{UpstreamsStore: stub.NewInMemoryUpstreamsStore()}
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/upstreams", strings.NewReader(`{"target":""}`))
+	findHandler(ctrl.GetHandlers(), "SetUpstream").Handler(response, request)
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "Upstream server address can't be empty.", response.Body.String())
+}
+
+func TestUpstreamsController_deleteUpstreamsHandler(t *testing.T) {
+	upstreamsStore := stub.NewInMemoryUpstreamsStore()
+	forward := &stub.StubForward{ServerAddress: "localhost:1"}
+	upstreamsStore.Set("", forward)
+	upstreamsStore.Set("pkg.Service", forward)
+	ctrl := UpstreamsController{UpstreamsStore: upstreamsStore}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodDelete, "/upstreams?target=", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteUpstreams").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, []stub.Upstream{{Target: "pkg.Service", Forward: forward}}, upstreamsStore.GetAll())
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodDelete, "/upstreams", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteUpstreams").Handler(response, request)
+	assert.Empty(t, upstreamsStore.GetAll())
+}
+
+func TestUpstreamsController_MethodAlias(t *testing.T) {
+	upstreamsStore := stub.NewInMemoryUpstreamsStore()
+	ctrl := UpstreamsController{UpstreamsStore: upstreamsStore, Service: aliasedMockService{}}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/upstreams", strings.NewReader(`{"target":"/pkg.Service/Method","forward":{"serverAddress":"localhost:1"}}`))
+	findHandler(ctrl.GetHandlers(), "SetUpstream").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, &stub.StubForward{ServerAddress: "localhost:1"}, upstreamsStore.Get("/pkg.Service/method"))
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodDelete, "/upstreams?target=/pkg.Service/Method", nil)
+	findHandler(ctrl.GetHandlers(), "DeleteUpstreams").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Nil(t, upstreamsStore.Get("/pkg.Service/method"))
+}
+
+func TestUpstreamsController_RedactsBearerToken(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	upstreamsStore := stub.NewInMemoryUpstreamsStore()
+	ctrl := UpstreamsController{UpstreamsStore: upstreamsStore}
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/upstreams", strings.NewReader(`{"target":"","forward":{"serverAddress":"localhost:1","credentials":{"bearerToken":"secret"}}}`))
+	findHandler(ctrl.GetHandlers(), "SetUpstream").Handler(response, request)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "secret", upstreamsStore.Resolve("/pkg.Service/Method").Credentials.BearerToken)
+
+	response = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/upstreams", nil)
+	findHandler(ctrl.GetHandlers(), "GetUpstreams").Handler(response, request)
+	assert.Equal(t, `[{"target":"","forward":{"serverAddress":"localhost:1","record":false,"credentials":{"bearerToken":"[REDACTED]"}}}]`, response.Body.String())
+	assert.Contains(t, hook.AllEntries()[0].Data["upstream"], `"bearerToken":"[REDACTED]"`)
+	for _, entry := range hook.AllEntries() {
+		for _, field := range entry.Data {
+			assert.NotContains(t, field, "secret")
+		}
+	}
+	assert.Equal(t, "secret", upstreamsStore.Resolve("/pkg.Service/Method").Credentials.BearerToken)
+}
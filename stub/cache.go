@@ -0,0 +1,65 @@
+package stub
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache keeps up to size values, evicting the least recently used one when a new value doesn't fit.
+// It is safe for concurrent use.
+type lruCache struct {
+	size    int
+	entries map[interface{}]*list.Element
+	order   *list.List // most recently used first
+	mutex   sync.Mutex
+}
+
+type lruCacheEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:    size,
+		entries: make(map[interface{}]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *lruCache) get(key interface{}) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruCacheEntry).value, true
+}
+
+func (c *lruCache) add(key, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruCacheEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruCacheEntry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+// len returns the number of values in the cache
+func (c *lruCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
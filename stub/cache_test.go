@@ -0,0 +1,29 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.add("a", 1)
+	cache.add("b", 2)
+
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// b is the least recently used value
+	cache.add("c", 3)
+	assert.Equal(t, 2, cache.len())
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	value, _ = cache.get("c")
+	assert.Equal(t, 3, value)
+
+	cache.add("a", 4)
+	value, _ = cache.get("a")
+	assert.Equal(t, 4, value)
+	assert.Equal(t, 2, cache.len())
+}
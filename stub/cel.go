@@ -0,0 +1,130 @@
+package stub
+
+import (
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"strings"
+)
+
+// Stubs whose request match is 'cel' are matched with a Common Expression Language expression returning a boolean.
+// The expression can use two variables:
+// - request: the request message of the method, typed after its proto definition
+// - metadata: the metadata of the call, a map from the key to its list of values
+// For example: request.user.age > 18 && metadata['x-tenant'][0] == 'acme'
+
+// maxCachedCelPrograms is the number of expressions kept compiled
+const maxCachedCelPrograms = 1000
+
+// celPrograms caches the expressions already compiled since the same stubs are evaluated on every call
+var celPrograms = newLRUCache(maxCachedCelPrograms)
+
+type celProgramKey struct {
+	request    protoreflect.FullName
+	expression string
+}
+
+// compileCelExpression parses and type checks the expression against the request message
+func compileCelExpression(expression string, request protoreflect.MessageDescriptor) (cel.Program, error) {
+	key := celProgramKey{request: request.FullName(), expression: expression}
+	if program, ok := celPrograms.get(key); ok {
+		return program.(cel.Program), nil
+	}
+	env, err := cel.NewEnv(
+		cel.Types(dynamicpb.NewMessage(request)),
+		cel.Declarations(
+			decls.NewVar("request", decls.NewObjectType(string(request.FullName()))),
+			decls.NewVar("metadata", decls.NewMapType(decls.String, decls.NewListType(decls.String))),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) {
+		return nil, fmt.Errorf("expression must return a bool but returns %s", cel.FormatType(ast.ResultType()))
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	celPrograms.add(key, program)
+	return program, nil
+}
+
+// matchCel evaluates the expression with the request and metadata of a call. Expressions failing to evaluate,
+// for instance because they read metadata not sent, don't match. The error is logged and reported by NearMisses.
+func matchCel(expression, fullMethod, requestJson string, md metadata.MD) bool {
+	matched, err := evaluateCel(expression, fullMethod, requestJson, md)
+	if err != nil {
+		log.Warnf("CEL expression of a stub for %s doesn't match since it failed: %s", fullMethod, err)
+	}
+	return matched
+}
+
+// evaluateCel tells whether the expression is true, returning an error when it can't be evaluated
+func evaluateCel(expression, fullMethod, requestJson string, md metadata.MD) (bool, error) {
+	descriptor, err := findRequestDescriptor(fullMethod)
+	if err != nil {
+		return false, err
+	}
+	program, err := compileCelExpression(expression, descriptor)
+	if err != nil {
+		return false, err
+	}
+	request := newMessage(descriptor)
+	if err := protojson.Unmarshal([]byte(requestJson), request); err != nil {
+		return false, fmt.Errorf("could not read the request: %w", err)
+	}
+	if md == nil {
+		md = metadata.MD{}
+	}
+	result, _, err := program.Eval(map[string]interface{}{
+		"request":  request,
+		"metadata": map[string][]string(md),
+	})
+	if err != nil {
+		return false, fmt.Errorf("expression %s could not be evaluated: %w", expression, err)
+	}
+	return result.Value() == true, nil
+}
+
+// findRequestDescriptor looks up the request message of a method like /package.Service/Method in the registered proto files
+func findRequestDescriptor(fullMethod string) (protoreflect.MessageDescriptor, error) {
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1))
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("method %s not found: %w", fullMethod, err)
+	}
+	method, ok := descriptor.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a method", fullMethod)
+	}
+	return method.Input(), nil
+}
+
+// newMessage creates an instance of the generated type of the message, or a dynamic message if it isn't registered
+func newMessage(descriptor protoreflect.MessageDescriptor) proto.Message {
+	if messageType, err := protoregistry.GlobalTypes.FindMessageByName(descriptor.FullName()); err == nil {
+		return messageType.New().Interface()
+	}
+	return dynamicpb.NewMessage(descriptor)
+}
+
+// isValidExpression checks the expression compiles against the request message
+func (s StubRequest) isValidExpression(request protoreflect.MessageDescriptor) (errMsgs []string) {
+	if _, err := compileCelExpression(s.Expression, request); err != nil {
+		errMsgs = append(errMsgs, fmt.Sprintf("Request expression is not valid: %s", err))
+	}
+	return errMsgs
+}
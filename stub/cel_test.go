@@ -0,0 +1,118 @@
+package stub
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+const healthCheck = "/grpc.health.v1.Health/Check"
+
+func TestMatchCel(t *testing.T) {
+	md := metadata.Pairs("x-tenant", "acme")
+	tests := []struct {
+		expression string
+		matches    bool
+	}{
+		{`request.service == 'orders'`, true},
+		{`request.service.startsWith('ord') && metadata['x-tenant'][0] == 'acme'`, true},
+		{`request.service == 'payments'`, false},
+		{`metadata['x-user'][0] == 'john'`, false}, // fails to evaluate since the key wasn't sent
+		{`'x-tenant' in metadata`, true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, matchCel(test.expression, healthCheck, `{"service":"orders"}`, md), test.expression)
+	}
+}
+
+func TestStubRequest_isValidExpression(t *testing.T) {
+	descriptor := (&grpc_health_v1.HealthCheckRequest{}).ProtoReflect().Descriptor()
+	valid := StubRequest{Match: "cel", Expression: `request.service != ''`}
+	assert.Empty(t, valid.isValidExpression(descriptor))
+
+	unknownField := StubRequest{Match: "cel", Expression: `request.name == 'x'`}
+	errMsgs := unknownField.isValidExpression(descriptor)
+	assert.Len(t, errMsgs, 1)
+	assert.Contains(t, errMsgs[0], "Request expression is not valid:")
+	assert.Contains(t, errMsgs[0], "undefined field 'name'")
+
+	notBool := StubRequest{Match: "cel", Expression: `request.service`}
+	assert.Equal(t, []string{"Request expression is not valid: expression must return a bool but returns string"}, notBool.isValidExpression(descriptor))
+}
+
+func TestIsStubValid_Cel(t *testing.T) {
+	descriptor := (&grpc_health_v1.HealthCheckRequest{}).ProtoReflect().Descriptor()
+	response := (&grpc_health_v1.HealthCheckResponse{}).ProtoReflect().Descriptor()
+	stub := &Stub{
+		FullMethod: healthCheck,
+		Type:       "mock",
+		Request:    &StubRequest{Match: "cel", Expression: `request.service == 'orders'`},
+		Response:   &StubResponse{Type: "success", Content: `{}`},
+	}
+	isValid, errMsgs := IsStubValid(stub, descriptor, response)
+	assert.True(t, isValid, errMsgs)
+
+	stub.Request.Expression = `request.service > 1`
+	isValid, errMsgs = IsStubValid(stub, descriptor, response)
+	assert.False(t, isValid)
+	assert.Len(t, errMsgs, 1)
+
+	stub.Request.Expression = ""
+	isValid, errMsgs = IsStubValid(stub, descriptor, response)
+	assert.False(t, isValid)
+	assert.Equal(t, []string{"Request expression can't be empty when the matching type is 'cel'."}, errMsgs)
+}
+
+func TestStubsMatcher_Match_Cel(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+	store.Add(&Stub{
+		FullMethod: healthCheck,
+		Type:       "mock",
+		Request:    &StubRequest{Match: "cel", Expression: `request.service == 'orders' && metadata['x-tenant'][0] == 'acme'`},
+		Response:   &StubResponse{Type: "success", Content: `{"status":"SERVING"}`},
+	})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "acme"))
+	assert.NotNil(t, matcher.Match(ctx, healthCheck, `{"service":"orders"}`))
+	assert.Nil(t, matcher.Match(context.Background(), healthCheck, `{"service":"orders"}`))
+
+	nearMisses := matcher.NearMisses(context.Background(), healthCheck, `{"service":"orders"}`)
+	assert.Len(t, nearMisses, 1)
+	assert.Contains(t, nearMisses[0].Differences[0], "could not be evaluated")
+}
+
+func TestMatchCel_LogsEvaluationErrors(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	assert.False(t, matchCel(`request.service == 'payments'`, healthCheck, `{"service":"orders"}`, nil))
+	assert.Empty(t, hook.AllEntries())
+
+	assert.False(t, matchCel(`metadata['x-user'][0] == 'john'`, healthCheck, `{"service":"orders"}`, nil))
+	if assert.Len(t, hook.AllEntries(), 1) {
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+		assert.Contains(t, hook.LastEntry().Message, "CEL expression of a stub for "+healthCheck+" doesn't match since it failed")
+		assert.Contains(t, hook.LastEntry().Message, "could not be evaluated")
+	}
+}
+
+func TestStubsMatcher_NearMisses_CelFalse(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+	store.Add(&Stub{
+		FullMethod: healthCheck,
+		Type:       "mock",
+		Request:    &StubRequest{Match: "cel", Expression: `request.service == 'payments'`},
+		Response:   &StubResponse{Type: "success", Content: `{"status":"SERVING"}`},
+	})
+
+	nearMisses := matcher.NearMisses(context.Background(), healthCheck, `{"service":"orders"}`)
+	if assert.Len(t, nearMisses, 1) {
+		assert.Equal(t, []string{"expression request.service == 'payments' is false"}, nearMisses[0].Differences)
+	}
+}
@@ -0,0 +1,65 @@
+package stub
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ResponseDelay is how long to wait before replying. It is either fixed or sampled from a distribution on every call.
+type ResponseDelay struct {
+	Type   string  `json:"type"`             // fixed | uniform | normal | lognormal
+	Fixed  uint32  `json:"fixed,omitempty"`  // milliseconds, when type = fixed
+	Min    uint32  `json:"min,omitempty"`    // milliseconds, when type = uniform
+	Max    uint32  `json:"max,omitempty"`    // milliseconds, when type = uniform
+	Mean   float64 `json:"mean,omitempty"`   // milliseconds, when type = normal
+	StdDev float64 `json:"stdDev,omitempty"` // milliseconds, when type = normal
+	Median float64 `json:"median,omitempty"` // milliseconds, when type = lognormal
+	Sigma  float64 `json:"sigma,omitempty"`  // standard deviation of the underlying normal distribution, when type = lognormal
+}
+
+// Duration returns the delay for a call. Negative samples are truncated to zero.
+func (d *ResponseDelay) Duration() time.Duration {
+	if d == nil {
+		return 0
+	}
+	var ms float64
+	switch d.Type {
+	case "fixed":
+		ms = float64(d.Fixed)
+	case "uniform":
+		ms = float64(d.Min) + randomFloat64()*float64(d.Max-d.Min)
+	case "normal":
+		ms = d.Mean + randomNormFloat64()*d.StdDev
+	case "lognormal":
+		ms = d.Median * math.Exp(randomNormFloat64()*d.Sigma)
+	}
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (d *ResponseDelay) isValid(baseName string) (errMsgs []string) {
+	if d == nil {
+		return nil
+	}
+	switch d.Type {
+	case "fixed":
+	case "uniform":
+		if d.Min > d.Max {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: min can't be greater than max.", baseName))
+		}
+	case "normal":
+		if d.StdDev < 0 {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: stdDev can't be negative.", baseName))
+		}
+	case "lognormal":
+		if d.Median <= 0 || d.Sigma < 0 {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: median must be positive and sigma can't be negative.", baseName))
+		}
+	default:
+		errMsgs = append(errMsgs, fmt.Sprintf("%s: type can only be either 'fixed', 'uniform', 'normal' or 'lognormal'.", baseName))
+	}
+	return errMsgs
+}
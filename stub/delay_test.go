@@ -0,0 +1,43 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestResponseDelay_Duration_Nil(t *testing.T) {
+	var delay *ResponseDelay
+	assert.Equal(t, time.Duration(0), delay.Duration())
+}
+
+func TestResponseDelay_Duration_Fixed(t *testing.T) {
+	delay := &ResponseDelay{Type: "fixed", Fixed: 150}
+	assert.Equal(t, 150*time.Millisecond, delay.Duration())
+}
+
+func TestResponseDelay_Duration_Uniform(t *testing.T) {
+	delay := &ResponseDelay{Type: "uniform", Min: 10, Max: 20}
+	for i := 0; i < 100; i++ {
+		d := delay.Duration()
+		assert.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond, d)
+	}
+}
+
+func TestResponseDelay_Duration_NormalIsNeverNegative(t *testing.T) {
+	delay := &ResponseDelay{Type: "normal", Mean: 0, StdDev: 100}
+	for i := 0; i < 100; i++ {
+		assert.True(t, delay.Duration() >= 0)
+	}
+}
+
+func TestResponseDelay_Duration_LogNormal(t *testing.T) {
+	delay := &ResponseDelay{Type: "lognormal", Median: 50, Sigma: 0}
+	assert.Equal(t, 50*time.Millisecond, delay.Duration())
+}
+
+func TestResponseDelay_isValid(t *testing.T) {
+	assert.Empty(t, (&ResponseDelay{Type: "uniform", Min: 1, Max: 2}).isValid("delay"))
+	assert.Equal(t, []string{"delay: min can't be greater than max."}, (&ResponseDelay{Type: "uniform", Min: 2, Max: 1}).isValid("delay"))
+	assert.Equal(t, []string{"delay: type can only be either 'fixed', 'uniform', 'normal' or 'lognormal'."}, (&ResponseDelay{Type: "pareto"}).isValid("delay"))
+}
@@ -0,0 +1,292 @@
+package stub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"sort"
+	"strings"
+)
+
+// maxNearMisses is the number of stubs reported when no stub matches a request
+const maxNearMisses = 3
+
+// NearMiss is a stub for the method called whose request didn't match, with the differences that made it fail
+type NearMiss struct {
+	Stub        *Stub    `json:"stub"`
+	Differences []string `json:"differences"`
+}
+
+// NearMisses returns the stubs for the method closest to matching the request, the ones with fewer differences first.
+// Stubs with the same number of differences are listed in the order Match tries them.
+func (m *stubsMatcher) NearMisses(ctx context.Context, fullMethod, requestJson string) []NearMiss {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return m.nearMisses(fullMethod, func(stub *Stub) ([]string, bool) {
+		if stub.Request.IsStream() || stub.isConversation() {
+			return nil, false
+		}
+		return diffRequest(fullMethod, stub.Request, requestJson, md), true
+	})
+}
+
+// NearStreamMisses returns the client streaming stubs for the method closest to matching the messages received, like NearMisses
+func (m *stubsMatcher) NearStreamMisses(ctx context.Context, fullMethod string, requestsJson []string) []NearMiss {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return m.nearMisses(fullMethod, func(stub *Stub) ([]string, bool) {
+		if !stub.Request.IsStream() {
+			return nil, false
+		}
+		return append(diffStream(stub.Request, requestsJson), diffMetadata(stub.Request, md)...), true
+	})
+}
+
+// NearConversationMisses returns the conversation stubs for the method closest to matching the metadata of the call, like NearMisses
+func (m *stubsMatcher) NearConversationMisses(ctx context.Context, fullMethod string) []NearMiss {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return m.nearMisses(fullMethod, func(stub *Stub) ([]string, bool) {
+		if !stub.isConversation() {
+			return nil, false
+		}
+		return diffMetadata(stub.Request, md), true
+	})
+}
+
+// nearMisses lists the differences with the call of the stubs diff applies to, the state of their scenario included
+func (m *stubsMatcher) nearMisses(fullMethod string, diff func(stub *Stub) ([]string, bool)) []NearMiss {
+	nearMisses := make([]NearMiss, 0)
+	for _, stub := range m.candidates(fullMethod) {
+		differences, ok := diff(stub)
+		if !ok {
+			continue
+		}
+		if !m.inRequiredState(stub) {
+			differences = append(differences, fmt.Sprintf("scenario %s: expected state %s but it is in state %s",
+				stub.Scenario, stub.RequiredState, m.ScenariosStore.GetState(stub.Scenario)))
+		}
+		nearMisses = append(nearMisses, NearMiss{Stub: stub, Differences: differences})
+	}
+	sort.SliceStable(nearMisses, func(i, j int) bool {
+		return len(nearMisses[i].Differences) < len(nearMisses[j].Differences)
+	})
+	if len(nearMisses) > maxNearMisses {
+		nearMisses = nearMisses[:maxNearMisses]
+	}
+	return nearMisses
+}
+
+// NearMissesReport describes the near misses in a human readable way
+func NearMissesReport(nearMisses []NearMiss) string {
+	if len(nearMisses) == 0 {
+		return "there are no stubs for the method"
+	}
+	lines := make([]string, 0, len(nearMisses)+1)
+	lines = append(lines, "closest stubs:")
+	for i, nearMiss := range nearMisses {
+		request := nearMiss.Stub.Request
+		if nearMiss.Stub.isConversation() {
+			lines = append(lines, fmt.Sprintf("%d) conversation: %s", i+1, strings.Join(nearMiss.Differences, "; ")))
+			continue
+		}
+		content := request.Content.String()
+		switch {
+		case request.IsStream():
+			content = fmt.Sprintf("%s messages of %s", request.StreamMatch, toJsonValue(request.Stream))
+		case request.Match == "jsonpath":
+			content = toJsonValue(request.Paths)
+		case request.Match == "cel":
+			content = request.Expression
+		}
+		lines = append(lines, fmt.Sprintf("%d) %s match on %s: %s", i+1, request.Match, content, strings.Join(nearMiss.Differences, "; ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffRequest lists where the request JSON and metadata diverge from the stub's request
+func diffRequest(fullMethod string, request *StubRequest, requestJson string, md metadata.MD) []string {
+	differences := make([]string, 0)
+	switch request.Match {
+	case "jsonpath":
+		differences = diffPaths(request.Paths, requestJson)
+	case "cel":
+		if matched, err := evaluateCel(request.Expression, fullMethod, requestJson, md); err != nil {
+			differences = append(differences, err.Error())
+		} else if !matched {
+			differences = append(differences, fmt.Sprintf("expression %s is false", request.Expression))
+		}
+	default:
+		differences = diffContent(request.Match, request.Content, requestJson)
+	}
+	return append(differences, diffMetadata(request, md)...)
+}
+
+// diffContent lists where the request JSON diverges from the content, compared with the match exact or partial
+func diffContent(match string, content JsonString, requestJson string) []string {
+	expected := make(map[string]interface{})
+	actual := make(map[string]interface{})
+	json.Unmarshal([]byte(content), &expected)
+	json.Unmarshal([]byte(requestJson), &actual)
+	return diffJson("", expected, actual, match == "exact")
+}
+
+// diffStream lists where the messages received in a client streaming call diverge from the stream of the request,
+// following its StreamMatch like matchStream
+func diffStream(request *StubRequest, requestsJson []string) []string {
+	differences := make([]string, 0)
+	switch request.StreamMatch {
+	case "all":
+		if len(request.Stream) != len(requestsJson) {
+			return append(differences, fmt.Sprintf("stream: expected %d messages but got %d", len(request.Stream), len(requestsJson)))
+		}
+		for i, content := range request.Stream {
+			for _, difference := range diffContent(request.Match, content, requestsJson[i]) {
+				differences = append(differences, fmt.Sprintf("message %d: %s", i+1, difference))
+			}
+		}
+	case "last":
+		if len(requestsJson) == 0 {
+			return append(differences, "stream: expected at least one message but got none")
+		}
+		for _, difference := range diffContent(request.Match, request.Stream[len(request.Stream)-1], requestsJson[len(requestsJson)-1]) {
+			differences = append(differences, fmt.Sprintf("last message: %s", difference))
+		}
+	case "any":
+		for _, content := range request.Stream {
+			found := false
+			for _, requestJson := range requestsJson {
+				if matchContent(request.Match, content, requestJson) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				differences = append(differences, fmt.Sprintf("stream: expected message %s is missing", content))
+			}
+		}
+	}
+	return differences
+}
+
+// diffMetadata lists where the metadata of the call diverges from the metadata of the request
+func diffMetadata(request *StubRequest, md metadata.MD) []string {
+	differences := make([]string, 0)
+	requestMetadata := getRequestMetadata(request)
+	keys := make([]string, 0, len(requestMetadata))
+	for key := range requestMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		single := &StubRequest{Metadata: map[string][]string{key: requestMetadata[key]}}
+		if !matchRequestMetadata(single, md) {
+			differences = append(differences, fmt.Sprintf("metadata %s: expected %v but got %v", key, requestMetadata[key], md.Get(key)))
+		}
+	}
+	metadataMatchers := getMetadataMatchers(request)
+	keys = make([]string, 0, len(metadataMatchers))
+	for key := range metadataMatchers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !metadataMatchers[key].matches(md.Get(key)) {
+			differences = append(differences, fmt.Sprintf("metadata %s: expected %s but got %v", key, metadataMatchers[key], md.Get(key)))
+		}
+	}
+	return differences
+}
+
+func diffJson(path string, expected, actual map[string]interface{}, mustBeEqual bool) []string {
+	differences := make([]string, 0)
+	for _, key := range sortedKeys(expected) {
+		fieldPath := joinPath(path, key)
+		value := expected[key]
+		otherValue, found := actual[key]
+		if operators, ok := asOperators(value); ok {
+			if matchOperators(operators, otherValue, found, mustBeEqual) {
+				continue
+			}
+			if found {
+				differences = append(differences, fmt.Sprintf("%s: expected %s but got %s", fieldPath, toJsonValue(value), toJsonValue(otherValue)))
+			} else {
+				differences = append(differences, fmt.Sprintf("%s: expected %s but it is missing", fieldPath, toJsonValue(value)))
+			}
+			continue
+		}
+		if !found {
+			differences = append(differences, fmt.Sprintf("%s: expected %s but it is missing", fieldPath, toJsonValue(value)))
+			continue
+		}
+		if fmt.Sprintf("%T", value) != fmt.Sprintf("%T", otherValue) {
+			differences = append(differences, fmt.Sprintf("%s: expected %s but got %s", fieldPath, toJsonValue(value), toJsonValue(otherValue)))
+			continue
+		}
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			differences = append(differences, diffJson(fieldPath, typedValue, otherValue.(map[string]interface{}), mustBeEqual)...)
+		case []interface{}:
+			otherItems := otherValue.([]interface{})
+			if len(typedValue) != len(otherItems) {
+				differences = append(differences, fmt.Sprintf("%s: expected %d items but got %d", fieldPath, len(typedValue), len(otherItems)))
+				continue
+			}
+			for _, item := range typedValue {
+				if !containsItem(otherItems, item, mustBeEqual) {
+					differences = append(differences, fmt.Sprintf("%s: expected item %s is missing", fieldPath, toJsonValue(item)))
+				}
+			}
+		default:
+			if value != otherValue {
+				differences = append(differences, fmt.Sprintf("%s: expected %s but got %s", fieldPath, toJsonValue(value), toJsonValue(otherValue)))
+			}
+		}
+	}
+	if mustBeEqual {
+		for _, key := range sortedKeys(actual) {
+			if _, found := expected[key]; !found {
+				differences = append(differences, fmt.Sprintf("%s: unexpected value %s", joinPath(path, key), toJsonValue(actual[key])))
+			}
+		}
+	}
+	return differences
+}
+
+func containsItem(items []interface{}, item interface{}, mustBeEqual bool) bool {
+	for _, otherItem := range items {
+		if matchValue(item, otherItem, mustBeEqual) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(jsonMap map[string]interface{}) []string {
+	keys := make([]string, 0, len(jsonMap))
+	for key := range jsonMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func toJsonValue(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
+
+// DiagnosticsRequest is a call to diagnose against the stubs of its method
+type DiagnosticsRequest struct {
+	FullMethod string              `json:"fullMethod"`
+	Request    JsonString          `json:"request"`
+	Metadata   map[string][]string `json:"metadata"`
+}
@@ -0,0 +1,112 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestStubsMatcher_NearMisses(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	closest := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: "{\"name\":\"a\",\"address\":{\"city\":\"Dublin\"}}"},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+	}
+	farthest := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request: &StubRequest{
+			Match:    "exact",
+			Content:  "{\"name\":\"b\",\"tags\":[\"x\"]}",
+			Metadata: map[string][]string{"tenant": {"t1"}},
+		},
+		Response: &StubResponse{Type: "success", Content: "{}"},
+	}
+	store.Add(closest)
+	store.Add(farthest)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("tenant", "t2"))
+	nearMisses := matcher.NearMisses(ctx, "method1", "{\"name\":\"a\",\"address\":{\"city\":\"Cork\"},\"id\":1}")
+	assert.Equal(t, 2, len(nearMisses))
+	assert.Equal(t, closest, nearMisses[0].Stub)
+	assert.Equal(t, []string{"address.city: expected \"Dublin\" but got \"Cork\""}, nearMisses[0].Differences)
+	assert.Equal(t, farthest, nearMisses[1].Stub)
+	assert.Equal(t, []string{
+		"name: expected \"b\" but got \"a\"",
+		"tags: expected [\"x\"] but it is missing",
+		"address: unexpected value {\"city\":\"Cork\"}",
+		"id: unexpected value 1",
+		"metadata tenant: expected [t1] but got [t2]",
+	}, nearMisses[1].Differences)
+}
+
+func TestStubsMatcher_NearStreamMisses(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	all := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Stream: []JsonString{"{\"name\":\"a\"}", "{\"name\":\"b\"}"}, StreamMatch: "all"},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+	}
+	anyMessage := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Stream: []JsonString{"{\"name\":\"c\"}"}, StreamMatch: "any", Metadata: map[string][]string{"tenant": {"t1"}}},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+	}
+	unary := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: "{}"},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+	}
+	store.Add(all)
+	store.Add(anyMessage)
+	store.Add(unary)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	nearMisses := matcher.NearStreamMisses(context.Background(), "method1", []string{"{\"name\":\"a\"}", "{\"name\":\"x\"}"})
+	assert.Equal(t, []NearMiss{
+		{Stub: all, Differences: []string{"message 2: name: expected \"b\" but got \"x\""}},
+		{Stub: anyMessage, Differences: []string{"stream: expected message {\"name\":\"c\"} is missing", "metadata tenant: expected [t1] but got []"}},
+	}, nearMisses)
+	assert.Equal(t, "closest stubs:\n1) partial match on all messages of [{\"name\":\"a\"},{\"name\":\"b\"}]: message 2: name: expected \"b\" but got \"x\"",
+		NearMissesReport(nearMisses[:1]))
+}
+
+func TestStubsMatcher_NearConversationMisses(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	conversation := &Stub{
+		FullMethod:    "method1",
+		Type:          "mock",
+		Request:       &StubRequest{Match: "exact", Metadata: map[string][]string{"tenant": {"t1"}}},
+		Response:      &StubResponse{Type: "conversation", Conversation: []ConversationStep{{Type: "close"}}},
+		Scenario:      "chat",
+		RequiredState: "OPEN",
+	}
+	store.Add(conversation)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("tenant", "t2"))
+	nearMisses := matcher.NearConversationMisses(ctx, "method1")
+	assert.Equal(t, []NearMiss{{Stub: conversation, Differences: []string{
+		"metadata tenant: expected [t1] but got [t2]",
+		"scenario chat: expected state OPEN but it is in state Started",
+	}}}, nearMisses)
+	assert.Equal(t, "closest stubs:\n1) conversation: metadata tenant: expected [t1] but got [t2]; scenario chat: expected state OPEN but it is in state Started",
+		NearMissesReport(nearMisses))
+	assert.Empty(t, matcher.NearMisses(ctx, "method1", "{}"))
+}
+
+func TestNearMissesReport(t *testing.T) {
+	assert.Equal(t, "there are no stubs for the method", NearMissesReport(nil))
+	report := NearMissesReport([]NearMiss{{
+		Stub:        &Stub{Request: &StubRequest{Match: "partial", Content: "{\"name\":\"a\"}"}},
+		Differences: []string{"name: expected \"a\" but got \"b\""},
+	}})
+	assert.Equal(t, "closest stubs:\n1) partial match on {\"name\":\"a\"}: name: expected \"a\" but got \"b\"", report)
+}
@@ -0,0 +1,186 @@
+package stub
+
+import (
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fault makes a percentage of the calls fail, wait longer or be aborted. Faults can be set on a stub, for the calls it matches,
+// or for all the calls to a method. They apply to both 'mock' and 'forward' stubs.
+// When several faults are set they are sampled independently: the delay is added first, then the call can be aborted
+// and, if it isn't, fail with the error.
+type Fault struct {
+	Error *ErrorFault `json:"error,omitempty"`
+	Delay *DelayFault `json:"delay,omitempty"`
+	Abort *AbortFault `json:"abort,omitempty"`
+}
+
+// ErrorFault fails a percentage of the calls with a status
+type ErrorFault struct {
+	Percentage float64 `json:"percentage"` // from 0 to 100
+	Code       uint32  `json:"code"`
+	Message    string  `json:"message"`
+}
+
+// DelayFault adds latency to a percentage of the calls
+type DelayFault struct {
+	Percentage float64        `json:"percentage"` // from 0 to 100
+	Delay      *ResponseDelay `json:"delay"`
+}
+
+// AbortFault ends a percentage of the calls abruptly before responding. The only type is 'connection': the connection
+// the call was made on is closed, which also ends the other calls on it. gRPC servers can't reset a single stream.
+type AbortFault struct {
+	Percentage float64 `json:"percentage"` // from 0 to 100
+	Type       string  `json:"type"`       // connection
+}
+
+// InjectedFault is the outcome of sampling a fault for a call
+type InjectedFault struct {
+	Delay time.Duration
+	Abort string // connection - empty when the call isn't aborted
+	Error error
+}
+
+// Inject samples the faults for a call
+func (f *Fault) Inject() InjectedFault {
+	injected := InjectedFault{}
+	if f == nil {
+		return injected
+	}
+	if f.Delay != nil && happens(f.Delay.Percentage) {
+		injected.Delay = f.Delay.Delay.Duration()
+	}
+	if f.Abort != nil && happens(f.Abort.Percentage) {
+		injected.Abort = f.Abort.Type
+		return injected
+	}
+	if f.Error != nil && happens(f.Error.Percentage) {
+		injected.Error = status.Error(codes.Code(f.Error.Code), f.Error.Message)
+	}
+	return injected
+}
+
+// happens tells whether an event with the percentage of chances happens on this call
+func happens(percentage float64) bool {
+	return randomFloat64()*100 < percentage
+}
+
+func (f *Fault) isValid(baseName string) (errMsgs []string) {
+	if f == nil {
+		return nil
+	}
+	if f.Error == nil && f.Delay == nil && f.Abort == nil {
+		errMsgs = append(errMsgs, fmt.Sprintf("%s must have at least one of 'error', 'delay' or 'abort'.", baseName))
+	}
+	if f.Error != nil {
+		errMsgs = append(errMsgs, isValidPercentage(f.Error.Percentage, baseName+" error")...)
+		if f.Error.Code == uint32(codes.OK) {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s error: code can't be OK.", baseName))
+		}
+	}
+	if f.Delay != nil {
+		errMsgs = append(errMsgs, isValidPercentage(f.Delay.Percentage, baseName+" delay")...)
+		if f.Delay.Delay == nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s delay: delay is mandatory.", baseName))
+		}
+		errMsgs = append(errMsgs, f.Delay.Delay.isValid(baseName+" delay")...)
+	}
+	if f.Abort != nil {
+		errMsgs = append(errMsgs, isValidPercentage(f.Abort.Percentage, baseName+" abort")...)
+		if f.Abort.Type != "connection" {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s abort: type can only be 'connection'.", baseName))
+		}
+	}
+	return errMsgs
+}
+
+// MethodFault is a fault applied to all the calls to a method, whether a stub matches them or not
+type MethodFault struct {
+	FullMethod string `json:"fullMethod"`
+	Fault      *Fault `json:"fault"`
+}
+
+// IsValid checks the fault set for all the calls to a method
+func (f *MethodFault) IsValid() (isValid bool, errMsgs []string) {
+	if f.FullMethod == "" {
+		errMsgs = append(errMsgs, "Full method name can't be empty.")
+	}
+	if f.Fault == nil {
+		errMsgs = append(errMsgs, "Fault can't be empty.")
+	}
+	errMsgs = append(errMsgs, f.Fault.isValid("Fault")...)
+	return len(errMsgs) == 0, errMsgs
+}
+
+func isValidPercentage(percentage float64, baseName string) []string {
+	if percentage < 0 || percentage > 100 {
+		return []string{fmt.Sprintf("%s: percentage must be between 0 and 100.", baseName)}
+	}
+	return nil
+}
+
+func NewInMemoryFaultsStore() FaultsStore {
+	return &inMemoryFaultsStore{
+		Faults: make(map[string]*Fault, 0),
+	}
+}
+
+type FaultsStore interface {
+	Get(fullMethod string) *Fault
+	Set(fullMethod string, fault *Fault)
+	GetAll() []MethodFault
+	Delete(fullMethod string)
+	DeleteAll()
+}
+
+type inMemoryFaultsStore struct {
+	Faults map[string]*Fault
+	mutex  sync.RWMutex
+}
+
+func (s *inMemoryFaultsStore) Get(fullMethod string) *Fault {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.Faults[fullMethod]
+}
+
+func (s *inMemoryFaultsStore) Set(fullMethod string, fault *Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Faults[fullMethod] = fault
+}
+
+func (s *inMemoryFaultsStore) GetAll() []MethodFault {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	faults := make([]MethodFault, 0, len(s.Faults))
+	for fullMethod, fault := range s.Faults {
+		faults = append(faults, MethodFault{FullMethod: fullMethod, Fault: fault})
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].FullMethod < faults[j].FullMethod
+	})
+	return faults
+}
+
+func (s *inMemoryFaultsStore) Delete(fullMethod string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.Faults, fullMethod)
+}
+
+func (s *inMemoryFaultsStore) DeleteAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Faults = make(map[string]*Fault, 0)
+}
@@ -0,0 +1,91 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestFault_Inject_Always(t *testing.T) {
+	fault := &Fault{
+		Error: &ErrorFault{Percentage: 100, Code: uint32(codes.Unavailable), Message: "boom"},
+		Delay: &DelayFault{Percentage: 100, Delay: &ResponseDelay{Type: "fixed", Fixed: 20}},
+	}
+	injected := fault.Inject()
+	assert.Equal(t, 20*time.Millisecond, injected.Delay)
+	assert.Equal(t, "", injected.Abort)
+	assert.Equal(t, codes.Unavailable, status.Code(injected.Error))
+	assert.Equal(t, "boom", status.Convert(injected.Error).Message())
+}
+
+func TestFault_Inject_Never(t *testing.T) {
+	fault := &Fault{
+		Error: &ErrorFault{Percentage: 0, Code: uint32(codes.Unavailable)},
+		Abort: &AbortFault{Percentage: 0, Type: "connection"},
+	}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, InjectedFault{}, fault.Inject())
+	}
+}
+
+func TestFault_Inject_AbortSkipsError(t *testing.T) {
+	fault := &Fault{
+		Error: &ErrorFault{Percentage: 100, Code: uint32(codes.Unavailable)},
+		Abort: &AbortFault{Percentage: 100, Type: "connection"},
+	}
+	assert.Equal(t, InjectedFault{Abort: "connection"}, fault.Inject())
+}
+
+func TestFault_Inject_Seeded(t *testing.T) {
+	fault := &Fault{Error: &ErrorFault{Percentage: 50, Code: uint32(codes.Internal)}}
+	sample := func() []bool {
+		failed := make([]bool, 0)
+		for i := 0; i < 20; i++ {
+			failed = append(failed, fault.Inject().Error != nil)
+		}
+		return failed
+	}
+	SetRandomSeed(42)
+	first := sample()
+	SetRandomSeed(42)
+	assert.Equal(t, first, sample())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func TestMethodFault_IsValid(t *testing.T) {
+	methodFault := &MethodFault{Fault: &Fault{
+		Error: &ErrorFault{Percentage: 120},
+		Delay: &DelayFault{Percentage: 10},
+		Abort: &AbortFault{Percentage: 10, Type: "socket"},
+	}}
+	isValid, errMsgs := methodFault.IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{
+		"Full method name can't be empty.",
+		"Fault error: percentage must be between 0 and 100.",
+		"Fault error: code can't be OK.",
+		"Fault delay: delay is mandatory.",
+		"Fault abort: type can only be 'connection'.",
+	}, errMsgs)
+
+	isValid, errMsgs = (&MethodFault{FullMethod: "method1", Fault: &Fault{}}).IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{"Fault must have at least one of 'error', 'delay' or 'abort'."}, errMsgs)
+}
+
+func TestInMemoryFaultsStore(t *testing.T) {
+	store := NewInMemoryFaultsStore()
+	fault := &Fault{Abort: &AbortFault{Percentage: 10, Type: "connection"}}
+	store.Set("method2", fault)
+	store.Set("method1", fault)
+	assert.Equal(t, fault, store.Get("method1"))
+	assert.Equal(t, []MethodFault{{FullMethod: "method1", Fault: fault}, {FullMethod: "method2", Fault: fault}}, store.GetAll())
+
+	store.Delete("method1")
+	assert.Nil(t, store.Get("method1"))
+	store.DeleteAll()
+	assert.Empty(t, store.GetAll())
+}
@@ -0,0 +1,146 @@
+package stub
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"strings"
+)
+
+// ForwardTLS is the TLS configuration of the connections to the server calls are forwarded to.
+// Certificates and keys are read from PEM files.
+type ForwardTLS struct {
+	CAFile             string `json:"caFile,omitempty"`             // CA certificates the server certificate is verified with. The system ones when empty.
+	ServerName         string `json:"serverName,omitempty"`         // name checked in the server certificate instead of the one in the server address
+	CertFile           string `json:"certFile,omitempty"`           // client certificate, for mTLS. Requires keyFile.
+	KeyFile            string `json:"keyFile,omitempty"`            // key of the client certificate
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // accepts any server certificate. Only meant for testing.
+}
+
+// ForwardCredentials are sent as the authorization metadata of the calls forwarded
+type ForwardCredentials struct {
+	BearerToken          string `json:"bearerToken,omitempty"`          // static token sent as 'Bearer <token>'
+	ForwardAuthorization bool   `json:"forwardAuthorization,omitempty"` // sends the authorization metadata the call was received with
+}
+
+// redactedSecret replaces the secrets of the forwards shown by the REST API or logged
+const redactedSecret = "[REDACTED]"
+
+// Redacted returns a copy of the forward with its bearer token masked, to be shown or logged.
+// The forward itself is returned when it has no secret.
+func (f *StubForward) Redacted() *StubForward {
+	if f == nil || f.Credentials == nil || f.Credentials.BearerToken == "" {
+		return f
+	}
+	forward := *f
+	credentials := *f.Credentials
+	credentials.BearerToken = redactedSecret
+	forward.Credentials = &credentials
+	return &forward
+}
+
+// Redacted returns a copy of the stub with the secrets of its forward masked, to be shown or logged.
+// The stub itself is returned when it has no secret.
+func (stub *Stub) Redacted() *Stub {
+	if stub == nil || stub.Forward.Redacted() == stub.Forward {
+		return stub
+	}
+	redacted := *stub
+	redacted.Forward = stub.Forward.Redacted()
+	return &redacted
+}
+
+// Config builds the TLS configuration, reading the certificates and keys
+func (t *ForwardTLS) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		ca, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no PEM certificate found in CA file %s", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// isValid checks the TLS configuration and credentials of the forward can be used
+func (f *StubForward) isValid(baseName string) (errMsgs []string) {
+	if f.TLS != nil {
+		if (f.TLS.CertFile == "") != (f.TLS.KeyFile == "") {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s TLS certFile and keyFile must be provided together.", baseName))
+		} else if _, err := f.TLS.Config(); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s TLS is not valid: %s", baseName, err))
+		}
+	}
+	if f.Credentials != nil && f.Credentials.BearerToken != "" && f.Credentials.ForwardAuthorization {
+		errMsgs = append(errMsgs, fmt.Sprintf("%s credentials can't combine 'bearerToken' with 'forwardAuthorization'.", baseName))
+	}
+	if f.Metadata != nil {
+		errMsgs = append(errMsgs, isValidResponseMetadata(f.Metadata.Set, baseName+" metadata")...)
+	}
+	return errMsgs
+}
+
+// ForwardMetadata tells which metadata of the call is sent to the server. Keys are case-insensitive.
+// Keys set by gRPC itself, like content-type or user-agent, are never propagated.
+type ForwardMetadata struct {
+	Allow []string            `json:"allow,omitempty"` // only these keys are propagated. All of them when empty.
+	Deny  []string            `json:"deny,omitempty"`  // keys not propagated, even if allowed
+	Set   map[string][]string `json:"set,omitempty"`   // keys added, or rewritten when the call has them
+}
+
+// OutgoingMetadata returns the metadata sent to the server for a call received with the incoming metadata
+func (f *StubForward) OutgoingMetadata(incoming metadata.MD) metadata.MD {
+	outgoing := metadata.MD{}
+	for key, values := range incoming {
+		key = strings.ToLower(key)
+		if isTransportMetadata(key) || !f.Metadata.propagates(key) {
+			continue
+		}
+		if f.Credentials != nil && key == "authorization" {
+			continue // sent by the credentials
+		}
+		outgoing[key] = append(outgoing[key], values...)
+	}
+	if f.Metadata != nil {
+		for key, values := range f.Metadata.Set {
+			outgoing[strings.ToLower(key)] = values
+		}
+	}
+	return outgoing
+}
+
+func (m *ForwardMetadata) propagates(key string) bool {
+	if m == nil {
+		return true
+	}
+	equalsKey := func(other string) bool { return strings.EqualFold(other, key) }
+	if len(m.Allow) > 0 && !containsValue(m.Allow, equalsKey) {
+		return false
+	}
+	return !containsValue(m.Deny, equalsKey)
+}
+
+// isTransportMetadata tells whether the key is set by gRPC or HTTP/2 on every call rather than by the caller
+func isTransportMetadata(key string) bool {
+	switch key {
+	case "content-type", "user-agent", "te":
+		return true
+	}
+	return strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-")
+}
@@ -0,0 +1,142 @@
+package stub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate and its key as PEM files in the directory
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestForwardTLS_Config(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forward-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := (&ForwardTLS{CAFile: certFile, ServerName: "upstream", CertFile: certFile, KeyFile: keyFile}).Config()
+	assert.Nil(t, err)
+	assert.Equal(t, "upstream", config.ServerName)
+	assert.NotNil(t, config.RootCAs)
+	assert.Equal(t, 1, len(config.Certificates))
+
+	_, err = (&ForwardTLS{CAFile: keyFile}).Config()
+	assert.EqualError(t, err, "no PEM certificate found in CA file "+keyFile)
+}
+
+func TestStubForward_isValid(t *testing.T) {
+	forward := &StubForward{
+		ServerAddress: "localhost:1",
+		TLS:           &ForwardTLS{CertFile: "cert.pem"},
+		Credentials:   &ForwardCredentials{BearerToken: "token", ForwardAuthorization: true},
+	}
+	assert.Equal(t, []string{
+		"Forward TLS certFile and keyFile must be provided together.",
+		"Forward credentials can't combine 'bearerToken' with 'forwardAuthorization'.",
+	}, forward.isValid("Forward"))
+
+	forward = &StubForward{ServerAddress: "localhost:1", TLS: &ForwardTLS{CAFile: "missing.pem"}}
+	assert.Equal(t, []string{
+		"Forward TLS is not valid: could not read CA file: open missing.pem: no such file or directory",
+	}, forward.isValid("Forward"))
+
+	forward = &StubForward{ServerAddress: "localhost:1", TLS: &ForwardTLS{}, Credentials: &ForwardCredentials{ForwardAuthorization: true}}
+	assert.Empty(t, forward.isValid("Forward"))
+}
+
+func TestStubForward_OutgoingMetadata(t *testing.T) {
+	incoming := metadata.MD{
+		":authority":    {"mock:10010"},
+		"content-type":  {"application/grpc"},
+		"user-agent":    {"grpc-go/1.35.0"},
+		"authorization": {"Bearer caller"},
+		"x-tenant":      {"t1"},
+		"x-trace-id":    {"abc"},
+		"x-debug":       {"true"},
+	}
+
+	forward := &StubForward{}
+	assert.Equal(t, metadata.MD{
+		"authorization": {"Bearer caller"},
+		"x-tenant":      {"t1"},
+		"x-trace-id":    {"abc"},
+		"x-debug":       {"true"},
+	}, forward.OutgoingMetadata(incoming))
+
+	forward = &StubForward{Metadata: &ForwardMetadata{
+		Allow: []string{"Authorization", "X-Tenant", "x-trace-id"},
+		Deny:  []string{"x-trace-id"},
+		Set:   map[string][]string{"X-Tenant": {"t2"}, "x-source": {"mock"}},
+	}}
+	assert.Equal(t, metadata.MD{
+		"authorization": {"Bearer caller"},
+		"x-tenant":      {"t2"},
+		"x-source":      {"mock"},
+	}, forward.OutgoingMetadata(incoming))
+
+	forward = &StubForward{Credentials: &ForwardCredentials{BearerToken: "token"}, Metadata: &ForwardMetadata{Allow: []string{"authorization"}}}
+	assert.Equal(t, metadata.MD{}, forward.OutgoingMetadata(incoming))
+}
+
+func TestStubForward_isValid_MetadataSet(t *testing.T) {
+	forward := &StubForward{ServerAddress: "localhost:1", Metadata: &ForwardMetadata{Set: map[string][]string{"grpc-timeout": {"1S"}}}}
+	assert.Equal(t, []string{"Forward metadata 'grpc-timeout' is reserved for gRPC."}, forward.isValid("Forward"))
+}
+
+func TestStubForward_Redacted(t *testing.T) {
+	forward := &StubForward{ServerAddress: "localhost:1", Credentials: &ForwardCredentials{BearerToken: "secret"}}
+
+	redacted := forward.Redacted()
+	assert.Equal(t, &StubForward{ServerAddress: "localhost:1", Credentials: &ForwardCredentials{BearerToken: "[REDACTED]"}}, redacted)
+	assert.Equal(t, "secret", forward.Credentials.BearerToken)
+
+	withoutToken := &StubForward{ServerAddress: "localhost:1", Credentials: &ForwardCredentials{ForwardAuthorization: true}}
+	assert.Same(t, withoutToken, withoutToken.Redacted())
+	assert.Nil(t, (*StubForward)(nil).Redacted())
+}
+
+func TestStub_Redacted(t *testing.T) {
+	s := &Stub{FullMethod: "method1", Type: "forward", Forward: &StubForward{ServerAddress: "localhost:1", Credentials: &ForwardCredentials{BearerToken: "secret"}}}
+
+	redacted := s.Redacted()
+	assert.Equal(t, "[REDACTED]", redacted.Forward.Credentials.BearerToken)
+	assert.Equal(t, "method1", redacted.FullMethod)
+	assert.Equal(t, "secret", s.Forward.Credentials.BearerToken)
+
+	mock := &Stub{FullMethod: "method1", Type: "mock"}
+	assert.Same(t, mock, mock.Redacted())
+	assert.Nil(t, (*Stub)(nil).Redacted())
+}
@@ -0,0 +1,89 @@
+package stub
+
+import (
+	"sync"
+	"time"
+)
+
+// JournalEntry is a call received by the mock server
+type JournalEntry struct {
+	FullMethod string              `json:"fullMethod"`
+	Request    JsonString          `json:"request"` // JSON array of the messages received for client and bidirectional streaming calls
+	Metadata   map[string][]string `json:"metadata"`
+	Peer       string              `json:"peer"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Stub       *Stub               `json:"stub"`               // nil when no stub matched the call
+	Response   JsonString          `json:"response,omitempty"` // JSON array of the messages sent for server and bidirectional streaming calls
+	Status     *ErrorResponse      `json:"status,omitempty"`   // nil when the call succeeded
+}
+
+// JournalFilter selects journal entries. Empty fields match all the entries.
+type JournalFilter struct {
+	FullMethod string
+	Since      time.Time
+	Until      time.Time
+}
+
+func (f JournalFilter) matches(entry JournalEntry) bool {
+	if f.FullMethod != "" && f.FullMethod != entry.FullMethod {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// NewInMemoryRequestJournal creates a journal that keeps the last maxEntries calls received
+func NewInMemoryRequestJournal(maxEntries int) RequestJournal {
+	return &inMemoryRequestJournal{
+		Entries:    make([]JournalEntry, 0),
+		MaxEntries: maxEntries,
+	}
+}
+
+type RequestJournal interface {
+	Add(entry JournalEntry)
+	Find(filter JournalFilter) []JournalEntry
+	Clear()
+}
+
+type inMemoryRequestJournal struct {
+	// Entries in the order the calls were received. The oldest entries are dropped when MaxEntries is reached.
+	Entries    []JournalEntry
+	MaxEntries int
+	mutex      sync.RWMutex
+}
+
+func (j *inMemoryRequestJournal) Add(entry JournalEntry) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.Entries = append(j.Entries, entry)
+	if overflow := len(j.Entries) - j.MaxEntries; overflow > 0 {
+		j.Entries = append(make([]JournalEntry, 0, j.MaxEntries), j.Entries[overflow:]...)
+	}
+}
+
+func (j *inMemoryRequestJournal) Find(filter JournalFilter) []JournalEntry {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	entries := make([]JournalEntry, 0)
+	for _, entry := range j.Entries {
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (j *inMemoryRequestJournal) Clear() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.Entries = make([]JournalEntry, 0)
+}
@@ -0,0 +1,34 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRequestJournal_DropsOldestEntries(t *testing.T) {
+	journal := NewInMemoryRequestJournal(2)
+	journal.Add(JournalEntry{FullMethod: "method1"})
+	journal.Add(JournalEntry{FullMethod: "method2"})
+	journal.Add(JournalEntry{FullMethod: "method3"})
+
+	entries := journal.Find(JournalFilter{})
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "method2", entries[0].FullMethod)
+	assert.Equal(t, "method3", entries[1].FullMethod)
+}
+
+func TestRequestJournal_Find(t *testing.T) {
+	now := time.Now()
+	journal := NewInMemoryRequestJournal(10)
+	journal.Add(JournalEntry{FullMethod: "method1", Timestamp: now.Add(-2 * time.Minute)})
+	journal.Add(JournalEntry{FullMethod: "method2", Timestamp: now.Add(-time.Minute)})
+	journal.Add(JournalEntry{FullMethod: "method1", Timestamp: now})
+
+	assert.Equal(t, 2, len(journal.Find(JournalFilter{FullMethod: "method1"})))
+	assert.Equal(t, 2, len(journal.Find(JournalFilter{Since: now.Add(-time.Minute)})))
+	assert.Equal(t, 1, len(journal.Find(JournalFilter{FullMethod: "method1", Until: now.Add(-time.Minute)})))
+
+	journal.Clear()
+	assert.Equal(t, 0, len(journal.Find(JournalFilter{})))
+}
@@ -0,0 +1,395 @@
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSON paths select values in the request JSON for stubs whose request match is 'jsonpath'. Supported syntax:
+// - $ : the root of the request
+// - .name or ['name'] : a field
+// - [n] : an element of an array. Negative indexes count from the end.
+// - .* or [*] : all the fields of an object or elements of an array
+// - ..name : the field at any depth
+// - [?(@.field == 'value')] : the elements of an array for which the condition holds, see pathCondition
+// For example: $.items[?(@.sku=='A1')].qty
+
+type pathSegmentKind int
+
+const (
+	childSegment pathSegmentKind = iota
+	indexSegment
+	wildcardSegment
+	recursiveSegment
+	filterSegment
+)
+
+type pathSegment struct {
+	kind   pathSegmentKind
+	name   string // field name of child and recursive segments
+	index  int
+	filter []pathCondition // conditions of a filter segment, all of them must hold
+}
+
+// pathCondition is a condition of a filter, like @.sku == 'A1'. It reads the field in a dotted path relative to the
+// element and compares it with ==, !=, >, >=, < or <= to a literal: a string in single quotes, a number, true, false
+// or null. Numbers are compared by value, including the 64-bit integers protojson writes as strings like "2". The greater
+// and less than operators only compare numbers. A path without comparison checks the field exists.
+// Conditions are combined with &&.
+type pathCondition struct {
+	fields   []string // path relative to the element filtered
+	operator string   // empty when checking the path exists
+	literal  interface{}
+}
+
+// conditionFieldRegex matches the names of the fields read by conditions, which are the JSON names of proto fields
+var conditionFieldRegex = regexp.MustCompile("^[0-9a-zA-Z_]+$")
+
+// maxCachedPaths is the number of paths kept parsed
+const maxCachedPaths = 1000
+
+// parsedPaths caches the paths already parsed since the same stubs are evaluated on every call
+var parsedPaths = newLRUCache(maxCachedPaths)
+
+func parseJsonPath(path string) ([]pathSegment, error) {
+	path = strings.TrimSpace(path)
+	if segments, ok := parsedPaths.get(path); ok {
+		return segments.([]pathSegment), nil
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %s must start with $", path)
+	}
+	segments, err := parsePathSegments(path[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	parsedPaths.add(path, segments)
+	return segments, nil
+}
+
+func parsePathSegments(path string) ([]pathSegment, error) {
+	segments := make([]pathSegment, 0)
+	for i := 0; i < len(path); {
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			name, next := readPathName(path, i+2)
+			if name == "" || name == "*" {
+				return nil, fmt.Errorf("missing field name at %d", i)
+			}
+			segments = append(segments, pathSegment{kind: recursiveSegment, name: name})
+			i = next
+		case path[i] == '.':
+			name, next := readPathName(path, i+1)
+			switch name {
+			case "":
+				return nil, fmt.Errorf("missing field name at %d", i)
+			case "*":
+				segments = append(segments, pathSegment{kind: wildcardSegment})
+			default:
+				segments = append(segments, pathSegment{kind: childSegment, name: name})
+			}
+			i = next
+		case path[i] == '[':
+			end := findClosingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] for [ at %d", i)
+			}
+			segment, err := parseBracketSegment(strings.TrimSpace(path[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at %d", path[i], i)
+		}
+	}
+	return segments, nil
+}
+
+func readPathName(path string, start int) (string, int) {
+	end := start
+	for end < len(path) && path[end] != '.' && path[end] != '[' {
+		end++
+	}
+	return strings.TrimSpace(path[start:end]), end
+}
+
+// findClosingBracket returns the position of the ] closing the [ at start, skipping the string literals of filters
+func findClosingBracket(path string, start int) int {
+	inString := false
+	for i := start + 1; i < len(path); i++ {
+		switch {
+		case path[i] == '\'':
+			inString = !inString
+		case path[i] == ']' && !inString:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseBracketSegment(content string) (pathSegment, error) {
+	switch {
+	case content == "*":
+		return pathSegment{kind: wildcardSegment}, nil
+	case strings.HasPrefix(content, "?"):
+		expression := strings.TrimSpace(content[1:])
+		if !strings.HasPrefix(expression, "(") || !strings.HasSuffix(expression, ")") {
+			return pathSegment{}, fmt.Errorf("filter %s must be enclosed in parentheses", content)
+		}
+		filter, err := parsePathFilter(expression[1 : len(expression)-1])
+		return pathSegment{kind: filterSegment, filter: filter}, err
+	case len(content) > 1 && strings.HasPrefix(content, "'") && strings.HasSuffix(content, "'"):
+		return pathSegment{kind: childSegment, name: content[1 : len(content)-1]}, nil
+	}
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return pathSegment{}, fmt.Errorf("invalid index %s", content)
+	}
+	return pathSegment{kind: indexSegment, index: index}, nil
+}
+
+func parsePathFilter(expression string) ([]pathCondition, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("empty filter")
+	}
+	filter := make([]pathCondition, 0)
+	for _, condition := range strings.Split(expression, "&&") {
+		parsedCondition, err := parsePathCondition(strings.TrimSpace(condition))
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, parsedCondition)
+	}
+	return filter, nil
+}
+
+func parsePathCondition(condition string) (pathCondition, error) {
+	left, operator, right := condition, "", ""
+	// the first operator in the condition is used so that literals can contain operators
+	position := len(condition)
+	for _, candidate := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if i := strings.Index(condition, candidate); i >= 0 && i < position {
+			position = i
+			left, operator, right = strings.TrimSpace(condition[:i]), candidate, strings.TrimSpace(condition[i+len(candidate):])
+		}
+	}
+	if !strings.HasPrefix(left, "@.") {
+		return pathCondition{}, fmt.Errorf("condition %s must start with @.", condition)
+	}
+	fields := strings.Split(left[2:], ".")
+	for _, field := range fields {
+		if !conditionFieldRegex.MatchString(field) {
+			return pathCondition{}, fmt.Errorf("invalid field name '%s' in condition %s", field, condition)
+		}
+	}
+	if operator == "" {
+		return pathCondition{fields: fields}, nil
+	}
+	literal, err := parsePathLiteral(right)
+	if err != nil {
+		return pathCondition{}, fmt.Errorf("invalid value %s in condition %s", right, condition)
+	}
+	return pathCondition{fields: fields, operator: operator, literal: literal}, nil
+}
+
+// parsePathLiteral reads a string in single quotes or a JSON number, boolean or null
+func parsePathLiteral(literal string) (interface{}, error) {
+	if len(literal) > 1 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		return literal[1 : len(literal)-1], nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		return nil, err
+	}
+	if _, isString := value.(string); isString {
+		return nil, fmt.Errorf("strings must be in single quotes")
+	}
+	return value, nil
+}
+
+// evaluateJsonPath returns the values selected by the path in the JSON document
+func evaluateJsonPath(segments []pathSegment, document interface{}) []interface{} {
+	values := []interface{}{document}
+	for _, segment := range segments {
+		next := make([]interface{}, 0)
+		for _, value := range values {
+			next = append(next, evaluateSegment(segment, value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func evaluateSegment(segment pathSegment, value interface{}) []interface{} {
+	switch segment.kind {
+	case childSegment:
+		if object, ok := value.(map[string]interface{}); ok {
+			if child, found := object[segment.name]; found {
+				return []interface{}{child}
+			}
+		}
+	case indexSegment:
+		if items, ok := value.([]interface{}); ok {
+			index := segment.index
+			if index < 0 {
+				index += len(items)
+			}
+			if index >= 0 && index < len(items) {
+				return []interface{}{items[index]}
+			}
+		}
+	case wildcardSegment:
+		return children(value)
+	case recursiveSegment:
+		return descendants(segment.name, value)
+	case filterSegment:
+		items, _ := value.([]interface{})
+		selected := make([]interface{}, 0)
+		for _, item := range items {
+			if matchPathFilter(segment.filter, item) {
+				selected = append(selected, item)
+			}
+		}
+		return selected
+	}
+	return nil
+}
+
+// children returns the elements of an array or the values of an object, ordered by field name
+func children(value interface{}) []interface{} {
+	switch typedValue := value.(type) {
+	case []interface{}:
+		return typedValue
+	case map[string]interface{}:
+		values := make([]interface{}, 0, len(typedValue))
+		for _, key := range sortedKeys(typedValue) {
+			values = append(values, typedValue[key])
+		}
+		return values
+	}
+	return nil
+}
+
+// descendants returns the values of the fields with the name at any depth
+func descendants(name string, value interface{}) []interface{} {
+	values := make([]interface{}, 0)
+	if object, ok := value.(map[string]interface{}); ok {
+		if child, found := object[name]; found {
+			values = append(values, child)
+		}
+	}
+	for _, child := range children(value) {
+		values = append(values, descendants(name, child)...)
+	}
+	return values
+}
+
+func matchPathFilter(filter []pathCondition, item interface{}) bool {
+	for _, condition := range filter {
+		if !matchPathCondition(condition, item) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPathCondition(condition pathCondition, item interface{}) bool {
+	value := item
+	for _, field := range condition.fields {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if value, ok = object[field]; !ok {
+			return false
+		}
+	}
+	number, isNumber := toNumber(value)
+	literalNumber, literalIsNumber := condition.literal.(float64)
+	switch condition.operator {
+	case "":
+		return true
+	case "==":
+		if isNumber && literalIsNumber {
+			return number == literalNumber
+		}
+		return value == condition.literal
+	case "!=":
+		if isNumber && literalIsNumber {
+			return number != literalNumber
+		}
+		return value != condition.literal
+	case ">":
+		return isNumber && literalIsNumber && number > literalNumber
+	case ">=":
+		return isNumber && literalIsNumber && number >= literalNumber
+	case "<":
+		return isNumber && literalIsNumber && number < literalNumber
+	case "<=":
+		return isNumber && literalIsNumber && number <= literalNumber
+	}
+	return false
+}
+
+// matchPaths tells whether every path selects at least one value in the request matching the expected value,
+// which can be an object of operators. A path selecting nothing only matches the $absent operator.
+func matchPaths(paths map[string]interface{}, requestJson string) bool {
+	return len(diffPaths(paths, requestJson)) == 0
+}
+
+// diffPaths lists the paths not matching the request and why
+func diffPaths(paths map[string]interface{}, requestJson string) []string {
+	var document interface{}
+	json.Unmarshal([]byte(requestJson), &document)
+	differences := make([]string, 0)
+	for _, path := range sortedKeys(paths) {
+		expected := paths[path]
+		segments, err := parseJsonPath(path)
+		if err != nil {
+			differences = append(differences, err.Error())
+			continue
+		}
+		values := evaluateJsonPath(segments, document)
+		if len(values) == 0 {
+			if operators, ok := asOperators(expected); !ok || !matchOperators(operators, nil, false, false) {
+				differences = append(differences, fmt.Sprintf("%s: expected %s but it is missing", path, toJsonValue(expected)))
+			}
+			continue
+		}
+		if !anyValueMatches(expected, values) {
+			differences = append(differences, fmt.Sprintf("%s: expected %s but got %s", path, toJsonValue(expected), toJsonValue(values)))
+		}
+	}
+	return differences
+}
+
+func anyValueMatches(expected interface{}, values []interface{}) bool {
+	for _, value := range values {
+		if matchValue(expected, value, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidPaths checks the paths can be parsed and the operators in their expected values exist
+func (s StubRequest) isValidPaths() (errMsgs []string) {
+	if len(s.Paths) == 0 {
+		return []string{"Request paths can't be empty when the matching type is 'jsonpath'."}
+	}
+	for _, path := range sortedKeys(s.Paths) {
+		if _, err := parseJsonPath(path); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("Request path is not valid: %s", err))
+			continue
+		}
+		if operators, ok := asOperators(s.Paths[path]); ok {
+			errMsgs = append(errMsgs, validateOperators(nil, operators, path)...)
+		}
+	}
+	return errMsgs
+}
@@ -0,0 +1,139 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchPaths(t *testing.T) {
+	request := `{"name":"John","address":{"city":"London"},"items":[{"sku":"A1","qty":3},{"sku":"B2","qty":1,"gift":true}],"tags":["x","y"]}`
+	tests := []struct {
+		paths   map[string]interface{}
+		matches bool
+	}{
+		{map[string]interface{}{"$.name": "John"}, true},
+		{map[string]interface{}{"$['name']": "John"}, true},
+		{map[string]interface{}{"$.address.city": "London"}, true},
+		{map[string]interface{}{"$.address.city": "Paris"}, false},
+		{map[string]interface{}{"$.items[?(@.sku=='A1')].qty": map[string]interface{}{"$gte": 2.0}}, true},
+		{map[string]interface{}{"$.items[?(@.sku=='B2')].qty": map[string]interface{}{"$gte": 2.0}}, false},
+		{map[string]interface{}{"$.items[?(@.qty > 2 && @.sku != 'B2')].sku": "A1"}, true},
+		{map[string]interface{}{"$.items[?(@.gift)].sku": "B2"}, true},
+		{map[string]interface{}{"$.items[0].sku": "A1"}, true},
+		{map[string]interface{}{"$.items[-1].sku": "B2"}, true},
+		{map[string]interface{}{"$.items[*].sku": "B2"}, true},
+		{map[string]interface{}{"$..city": "London"}, true},
+		{map[string]interface{}{"$.tags.*": "y"}, true},
+		{map[string]interface{}{"$.nickname": map[string]interface{}{"$absent": true}}, true},
+		{map[string]interface{}{"$.name": map[string]interface{}{"$absent": true}}, false},
+		{map[string]interface{}{"$.nickname": "Johnny"}, false},
+		{map[string]interface{}{"$.name": "John", "$.tags[1]": "x"}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, matchPaths(test.paths, request), "%v", test.paths)
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	request := `{"items":[{"sku":"A1","qty":1}]}`
+	paths := map[string]interface{}{
+		"$.items[?(@.sku=='A1')].qty": map[string]interface{}{"$gte": 2.0},
+		"$.name":                      "John",
+	}
+	assert.Equal(t, []string{
+		`$.items[?(@.sku=='A1')].qty: expected {"$gte":2} but got [1]`,
+		`$.name: expected "John" but it is missing`,
+	}, diffPaths(paths, request))
+}
+
+func TestMatchPaths_Filters(t *testing.T) {
+	request := `{"items":[{"sku":"A1","qty":3,"price":{"amount":10}},{"sku":"B==2","qty":1,"gift":true,"note":null}]}`
+	tests := []struct {
+		path    string
+		matches bool
+	}{
+		{"$.items[?(@.sku == 'B==2')].qty", true},
+		{"$.items[?(@.qty >= 3)].sku", true},
+		{"$.items[?(@.qty < 3)].sku", false},
+		{"$.items[?(@.price.amount <= 10)].sku", true},
+		{"$.items[?(@.gift == true && @.note == null)].qty", true},
+		{"$.items[?(@.gift == false)].qty", false},
+		{"$.items[?(@.sku != 'A1' && @.qty > 5)].sku", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, matchPaths(map[string]interface{}{test.path: "A1"}, request) ||
+			matchPaths(map[string]interface{}{test.path: 1.0}, request), test.path)
+	}
+}
+
+func TestMatchPaths_FiltersInt64(t *testing.T) {
+	// protojson writes int64 fields as strings
+	request := `{"items":[{"sku":"A1","qty":"2"},{"sku":"B1","qty":"5"}]}`
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"$.items[?(@.qty == 2)].sku", "A1"},
+		{"$.items[?(@.qty != 2)].sku", "B1"},
+		{"$.items[?(@.qty >= 5)].sku", "B1"},
+		{"$.items[?(@.qty == '5')].sku", "B1"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, []string{}, diffPaths(map[string]interface{}{test.path: test.expected}, request), test.path)
+	}
+	assert.False(t, matchPaths(map[string]interface{}{"$.items[?(@.qty != 2)].sku": "A1"}, request))
+	assert.False(t, matchPaths(map[string]interface{}{"$.items[?(@.qty == 3)].sku": "A1"}, request))
+}
+
+func TestParseJsonPath_Unsupported(t *testing.T) {
+	for path, expectedErr := range map[string]string{
+		"$..*":                        "invalid path $..*: missing field name at 0",
+		`$["name"]`:                   `invalid path $["name"]: invalid index "name"`,
+		"$.items[?(@.sku == \"A1\")]": "invalid path $.items[?(@.sku == \"A1\")]: invalid value \"A1\" in condition @.sku == \"A1\"",
+		"$.items[?(@.a || @.b)]":      "invalid path $.items[?(@.a || @.b)]: invalid field name 'a || @' in condition @.a || @.b",
+		"$.items[?(sku == 'A1')]":     "invalid path $.items[?(sku == 'A1')]: condition sku == 'A1' must start with @.",
+	} {
+		_, err := parseJsonPath(path)
+		if assert.NotNil(t, err, path) {
+			assert.Equal(t, expectedErr, err.Error())
+		}
+	}
+}
+
+func TestStubRequest_isValidPaths(t *testing.T) {
+	request := StubRequest{Match: "jsonpath", Paths: map[string]interface{}{
+		"items[0]":    1.0,
+		"$.items[0":   1.0,
+		"$.name":      map[string]interface{}{"$prefix": 1.0},
+		"$..sku":      "A1",
+		"$.tags[?()]": "x",
+	}}
+	assert.Equal(t, []string{
+		"Request path is not valid: invalid path $.items[0: missing ] for [ at 6",
+		"Operator '$prefix' of field '$.name' expects a string.",
+		"Request path is not valid: invalid path $.tags[?()]: empty filter",
+		"Request path is not valid: path items[0] must start with $",
+	}, request.isValidPaths())
+
+	empty := StubRequest{Match: "jsonpath"}
+	assert.Equal(t, []string{"Request paths can't be empty when the matching type is 'jsonpath'."}, empty.isValidPaths())
+}
+
+func TestStubsMatcher_Match_JsonPath(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+	store.Add(&Stub{
+		FullMethod: "/svc/Method",
+		Type:       "mock",
+		Request: &StubRequest{Match: "jsonpath", Paths: map[string]interface{}{
+			"$.items[?(@.sku=='A1')].qty": map[string]interface{}{"$gte": 2.0},
+		}},
+		Response: &StubResponse{Type: "success", Content: `{"result":"bulk"}`},
+	})
+
+	stub := matcher.Match(context.Background(), "/svc/Method", `{"items":[{"sku":"B2","qty":1},{"sku":"A1","qty":2}]}`)
+	assert.NotNil(t, stub)
+	assert.Equal(t, JsonString(`{"result":"bulk"}`), stub.Response.Content)
+	assert.Nil(t, matcher.Match(context.Background(), "/svc/Method", `{"items":[{"sku":"A1","qty":1}]}`))
+}
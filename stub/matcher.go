@@ -10,37 +10,55 @@ import (
 // Search and match stubs in the StubsStore
 type StubsMatcher interface {
 	Match(ctx context.Context, fullMethod, requestJson string) *Stub
+	MatchStream(ctx context.Context, fullMethod string, requestsJson []string) *Stub
+	MatchConversation(ctx context.Context, fullMethod string) *Stub
+	NearMisses(ctx context.Context, fullMethod, requestJson string) []NearMiss
+	NearStreamMisses(ctx context.Context, fullMethod string, requestsJson []string) []NearMiss
+	NearConversationMisses(ctx context.Context, fullMethod string) []NearMiss
 }
 
 // Creates new stubs matcher
-func NewStubsMatcher(store StubsStore) StubsMatcher {
+func NewStubsMatcher(store StubsStore, scenarios ScenariosStore) StubsMatcher {
 	return &stubsMatcher{
-		StubsStore: store,
+		StubsStore:     store,
+		ScenariosStore: scenarios,
 	}
 }
 
 type stubsMatcher struct {
-	StubsStore StubsStore
+	StubsStore     StubsStore
+	ScenariosStore ScenariosStore
 }
 
-// Returns the Stub in the StubsStore that matches the method and requestJSON provided OR nil if no stub is found
+// Returns the Stub in the StubsStore that matches the method and requestJSON provided OR nil if no stub is found.
+// When several stubs match, an exact match is preferred over a partial, jsonpath or cel one. Among the matches of the same kind
+// the stub with the highest priority wins and, for the same priority, the one added first.
 func (m *stubsMatcher) Match(ctx context.Context, fullMethod, requestJson string) *Stub {
-	stubsForMethod := m.StubsStore.GetStubsMapForMethod(fullMethod)
-	if stubsForMethod == nil {
-		return nil
-	}
+	return m.matchAndTransition(func() *Stub {
+		return m.match(ctx, fullMethod, requestJson)
+	})
+}
+
+func (m *stubsMatcher) match(ctx context.Context, fullMethod, requestJson string) *Stub {
+	md, _ := metadata.FromIncomingContext(ctx)
 	var firstPartialMatch *Stub
-	for _, stub := range stubsForMethod {
+	for _, stub := range m.candidates(fullMethod) {
+		if stub.Request.IsStream() || stub.isConversation() {
+			continue // streaming stubs are only matched by MatchStream and MatchConversation
+		}
+		if !m.inRequiredState(stub) {
+			continue
+		}
 		switch stub.Request.Match {
 		case "exact":
 			if stub.Request.Content.Equals(JsonString(requestJson)) && matchMetadata(ctx, stub) {
 				return stub
 			}
-		case "partial":
+		case "partial", "jsonpath", "cel":
 			if firstPartialMatch != nil {
 				continue // if a partial match is already found continue (so it takes the first match)
 			}
-			if stub.Request.Content.Matches(JsonString(requestJson)) && matchMetadata(ctx, stub) {
+			if stub.Request.matches(fullMethod, requestJson, md) && matchMetadata(ctx, stub) {
 				firstPartialMatch = stub // Use the first partial match
 			}
 		}
@@ -48,34 +66,191 @@ func (m *stubsMatcher) Match(ctx context.Context, fullMethod, requestJson string
 	return firstPartialMatch
 }
 
-func matchMetadata(ctx context.Context, stub *Stub) bool {
-	if len(stub.Request.Metadata) == 0 {
+// Returns the Stub in the StubsStore that matches the method and the messages received in a client streaming call OR nil if no stub is found.
+// Ties are resolved like in Match.
+func (m *stubsMatcher) MatchStream(ctx context.Context, fullMethod string, requestsJson []string) *Stub {
+	return m.matchAndTransition(func() *Stub {
+		return m.matchStream(ctx, fullMethod, requestsJson)
+	})
+}
+
+func (m *stubsMatcher) matchStream(ctx context.Context, fullMethod string, requestsJson []string) *Stub {
+	var firstPartialMatch *Stub
+	for _, stub := range m.candidates(fullMethod) {
+		if !stub.Request.IsStream() || !m.inRequiredState(stub) || !matchStream(stub.Request, requestsJson) || !matchMetadata(ctx, stub) {
+			continue
+		}
+		if stub.Request.Match == "exact" {
+			return stub
+		}
+		if firstPartialMatch == nil {
+			firstPartialMatch = stub // Use the first partial match
+		}
+	}
+	return firstPartialMatch
+}
+
+// Returns the conversation Stub in the StubsStore for the method of a bidirectional streaming call OR nil if no stub is found.
+// Conversations are selected by the call's metadata only. When several stubs match, the one with more metadata keys is used,
+// then the one with the highest priority and then the one added first.
+func (m *stubsMatcher) MatchConversation(ctx context.Context, fullMethod string) *Stub {
+	return m.matchAndTransition(func() *Stub {
+		return m.matchConversation(ctx, fullMethod)
+	})
+}
+
+func (m *stubsMatcher) matchConversation(ctx context.Context, fullMethod string) *Stub {
+	var bestMatch *Stub
+	for _, stub := range m.candidates(fullMethod) {
+		if !stub.isConversation() || !m.inRequiredState(stub) || !matchMetadata(ctx, stub) {
+			continue
+		}
+		if bestMatch == nil || stub.Request.metadataKeys() > bestMatch.Request.metadataKeys() {
+			bestMatch = stub
+		}
+	}
+	return bestMatch
+}
+
+// candidates returns the stubs for the method in the order they are tried: highest priority first and,
+// for the same priority, in the order they were added to the store
+func (m *stubsMatcher) candidates(fullMethod string) []*Stub {
+	stubs := make([]*Stub, 0)
+	for _, stub := range m.StubsStore.GetStubsMapForMethod(fullMethod) {
+		stubs = append(stubs, stub)
+	}
+	sort.Slice(stubs, func(i, j int) bool {
+		if stubs[i].Priority != stubs[j].Priority {
+			return stubs[i].Priority > stubs[j].Priority
+		}
+		return stubs[i].added < stubs[j].added
+	})
+	return stubs
+}
+
+// inRequiredState tells whether the scenario of the stub is in the state the stub requires to match
+func (m *stubsMatcher) inRequiredState(stub *Stub) bool {
+	if stub.Scenario == "" || stub.RequiredState == "" {
+		return true
+	}
+	return m.ScenariosStore.GetState(stub.Scenario) == stub.RequiredState
+}
+
+// matchAndTransition finds the stub matching the call and moves its scenario to the new state. When another call moved
+// the scenario out of the state the stub requires after it was found, the stub no longer matches and the search is repeated.
+func (m *stubsMatcher) matchAndTransition(find func() *Stub) *Stub {
+	for {
+		stub := find()
+		if m.transition(stub) {
+			return stub
+		}
+	}
+}
+
+// transition moves the scenario of the stub matched to its new state. The required state is checked again in the same
+// step so that two concurrent calls can't both match a stub that moves the scenario out of the state it requires.
+// It tells whether the stub still matches.
+func (m *stubsMatcher) transition(stub *Stub) bool {
+	if stub == nil || stub.Scenario == "" {
+		return true
+	}
+	if stub.RequiredState == "" {
+		if stub.NewState != "" {
+			m.ScenariosStore.SetState(stub.Scenario, stub.NewState)
+		}
+		return true
+	}
+	newState := stub.NewState
+	if newState == "" {
+		newState = stub.RequiredState
+	}
+	return m.ScenariosStore.CompareAndSetState(stub.Scenario, stub.RequiredState, newState)
+}
+
+// matchStream compares the messages received with the stub's stream according to its StreamMatch:
+// - all: every message received matches the stream entry in the same position
+// - last: the last message received matches the last entry of the stream
+// - any: every entry of the stream matches at least one of the messages received
+func matchStream(request *StubRequest, requestsJson []string) bool {
+	switch request.StreamMatch {
+	case "all":
+		if len(request.Stream) != len(requestsJson) {
+			return false
+		}
+		for i, content := range request.Stream {
+			if !matchContent(request.Match, content, requestsJson[i]) {
+				return false
+			}
+		}
+		return true
+	case "last":
+		if len(requestsJson) == 0 {
+			return false
+		}
+		return matchContent(request.Match, request.Stream[len(request.Stream)-1], requestsJson[len(requestsJson)-1])
+	case "any":
+		for _, content := range request.Stream {
+			found := false
+			for _, requestJson := range requestsJson {
+				if matchContent(request.Match, content, requestJson) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
 		return true
 	}
-	// read metadata from context
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return false
+	return false
+}
+
+func matchContent(match string, content JsonString, requestJson string) bool {
+	switch match {
+	case "exact":
+		return content.Equals(JsonString(requestJson))
+	case "partial":
+		return content.Matches(JsonString(requestJson))
+	}
+	return false
+}
+
+func matchMetadata(ctx context.Context, stub *Stub) bool {
+	if len(stub.Request.Metadata) == 0 && len(stub.Request.MetadataMatch) == 0 {
+		return true
 	}
-	stubMetadata := getStubMetadata(stub)
+	// read metadata from context. A call without metadata can still match keys that must be absent.
+	md, _ := metadata.FromIncomingContext(ctx)
+	return matchRequestMetadata(stub.Request, md)
+}
+
+// matchRequestMetadata tells whether every key in the request's metadata has the same values in md
+// and every metadata matcher of the request holds
+func matchRequestMetadata(request *StubRequest, md metadata.MD) bool {
+	requestMetadata := getRequestMetadata(request)
 	// compare
-	for key, values := range stubMetadata {
-		contextMetadata := md.Get(key)
-		sort.Strings(contextMetadata)
-		sort.Strings(values)
-		if strings.Join(values, ",") != strings.Join(contextMetadata, ",") {
+	for key, values := range requestMetadata {
+		if !equalValues(values, md.Get(key)) {
 			return false
 		}
 	}
-	return true
+	return matchMetadataMatchers(request, md)
 }
 
-func getStubMetadata(stub *Stub) (stubMetadata map[string][]string) {
-	stubMetadata = make(map[string][]string, 0)
-	for key, values := range stub.Request.Metadata {
+// getRequestMetadata returns the metadata of the request with their keys in lower case, like the keys of metadata.MD
+func getRequestMetadata(request *StubRequest) (requestMetadata map[string][]string) {
+	requestMetadata = make(map[string][]string, 0)
+	for key, values := range request.Metadata {
+		key = strings.ToLower(key)
 		for _, value := range values {
-			stubMetadata[key] = append(stubMetadata[key], strings.TrimSpace(value))
+			requestMetadata[key] = append(requestMetadata[key], strings.TrimSpace(value))
 		}
 	}
 	return
 }
+
+// metadataKeys is the number of metadata keys the request matches on
+func (s StubRequest) metadataKeys() int {
+	return len(s.Metadata) + len(s.MetadataMatch)
+}
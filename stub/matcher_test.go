@@ -0,0 +1,218 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"sync"
+	"testing"
+)
+
+func newStreamStub(streamMatch string, stream ...JsonString) *Stub {
+	return &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request: &StubRequest{
+			Match:       "partial",
+			Stream:      stream,
+			StreamMatch: streamMatch,
+		},
+		Response: &StubResponse{
+			Type:    "success",
+			Content: "{}",
+		},
+	}
+}
+
+func TestStubsMatcher_MatchStream_All(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	store.Add(newStreamStub("all", "{\"name\":\"a\"}", "{\"name\":\"b\"}"))
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	assert.NotNil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"a\"}", "{\"name\":\"b\",\"id\":1}"}))
+	assert.Nil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"b\"}", "{\"name\":\"a\"}"}))
+	assert.Nil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"a\"}"}))
+}
+
+func TestStubsMatcher_MatchStream_Last(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	store.Add(newStreamStub("last", "{\"name\":\"b\"}"))
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	assert.NotNil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"a\"}", "{\"name\":\"b\"}"}))
+	assert.Nil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"b\"}", "{\"name\":\"a\"}"}))
+	assert.Nil(t, matcher.MatchStream(context.Background(), "method1", []string{}))
+}
+
+func TestStubsMatcher_MatchStream_Any(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	store.Add(newStreamStub("any", "{\"name\":\"b\"}", "{\"name\":\"c\"}"))
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	assert.NotNil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"c\"}", "{\"name\":\"a\"}", "{\"name\":\"b\"}"}))
+	assert.Nil(t, matcher.MatchStream(context.Background(), "method1", []string{"{\"name\":\"a\"}", "{\"name\":\"b\"}"}))
+}
+
+func TestStubsMatcher_Match_IgnoresStreamStubs(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	store.Add(newStreamStub("all", "{}"))
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	assert.Nil(t, matcher.Match(context.Background(), "method1", "{}"))
+}
+
+func newConversationStub(md map[string][]string) *Stub {
+	return &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request: &StubRequest{
+			Metadata: md,
+		},
+		Response: &StubResponse{
+			Type:         "conversation",
+			Conversation: []ConversationStep{{Type: "close"}},
+		},
+	}
+}
+
+func TestStubsMatcher_MatchConversation_PrefersMoreSpecificMetadata(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	generic := newConversationStub(nil)
+	specific := newConversationStub(map[string][]string{"room": {"1"}})
+	store.Add(generic)
+	store.Add(specific)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("room", "1"))
+	assert.Equal(t, specific, matcher.MatchConversation(ctx, "method1"))
+	assert.Equal(t, generic, matcher.MatchConversation(context.Background(), "method1"))
+	assert.Nil(t, matcher.Match(ctx, "method1", "{}"))
+}
+
+func TestStubsMatcher_Match_ScenarioStates(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	pending := &Stub{
+		FullMethod:    "GetOrder",
+		Type:          "mock",
+		Request:       &StubRequest{Match: "partial", Content: "{}"},
+		Response:      &StubResponse{Type: "success", Content: "{\"status\":\"PENDING\"}"},
+		Scenario:      "order",
+		RequiredState: ScenarioStarted,
+	}
+	cancelled := &Stub{
+		FullMethod:    "GetOrder",
+		Type:          "mock",
+		Request:       &StubRequest{Match: "partial", Content: "{}"},
+		Response:      &StubResponse{Type: "success", Content: "{\"status\":\"CANCELLED\"}"},
+		Scenario:      "order",
+		RequiredState: "CANCELLED",
+	}
+	cancel := &Stub{
+		FullMethod: "CancelOrder",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: "{}"},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+		Scenario:   "order",
+		NewState:   "CANCELLED",
+	}
+	assert.Nil(t, store.Add(pending))
+	assert.Nil(t, store.Add(cancelled))
+	assert.Nil(t, store.Add(cancel))
+	scenarios := NewInMemoryScenariosStore()
+	matcher := NewStubsMatcher(store, scenarios)
+
+	assert.Equal(t, pending, matcher.Match(context.Background(), "GetOrder", "{}"))
+	assert.Equal(t, cancel, matcher.Match(context.Background(), "CancelOrder", "{}"))
+	assert.Equal(t, "CANCELLED", scenarios.GetState("order"))
+	assert.Equal(t, cancelled, matcher.Match(context.Background(), "GetOrder", "{}"))
+
+	scenarios.Reset("order")
+	assert.Equal(t, pending, matcher.Match(context.Background(), "GetOrder", "{}"))
+}
+
+func TestStubsMatcher_Match_ScenarioStates_Concurrent(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	first := &Stub{
+		FullMethod:    "CreateOrder",
+		Type:          "mock",
+		Request:       &StubRequest{Match: "partial", Content: "{}"},
+		Response:      &StubResponse{Type: "success", Content: "{\"status\":\"CREATED\"}"},
+		Scenario:      "order",
+		RequiredState: ScenarioStarted,
+		NewState:      "CREATED",
+		Priority:      1,
+	}
+	duplicate := &Stub{
+		FullMethod: "CreateOrder",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: "{}"},
+		Response:   &StubResponse{Type: "error", Error: &ErrorResponse{Code: 6, Message: "order already exists"}},
+	}
+	assert.Nil(t, store.Add(first))
+	assert.Nil(t, store.Add(duplicate))
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	const calls = 50
+	matches := make(chan *Stub, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			matches <- matcher.Match(context.Background(), "CreateOrder", "{}")
+		}()
+	}
+	wg.Wait()
+	close(matches)
+
+	firstMatches := 0
+	for match := range matches {
+		if match == first {
+			firstMatches++
+		}
+	}
+	assert.Equal(t, 1, firstMatches)
+}
+
+func newPartialStub(content JsonString, priority int) *Stub {
+	return &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: content},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+		Priority:   priority,
+	}
+}
+
+func TestStubsMatcher_Match_PartialMatchesByPriorityThenInsertionOrder(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	catchAll := newPartialStub("{}", 0)
+	first := newPartialStub("{\"name\":\"a\"}", 10)
+	second := newPartialStub("{\"id\":1}", 10)
+	store.Add(catchAll)
+	store.Add(first)
+	store.Add(second)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, matcher.Match(context.Background(), "method1", "{\"name\":\"a\",\"id\":1}"))
+	}
+	assert.Equal(t, second, matcher.Match(context.Background(), "method1", "{\"name\":\"b\",\"id\":1}"))
+	assert.Equal(t, catchAll, matcher.Match(context.Background(), "method1", "{\"name\":\"b\"}"))
+}
+
+func TestStubsMatcher_Match_ExactMatchPreferredOverPriority(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	partial := newPartialStub("{}", 100)
+	exact := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "exact", Content: "{\"name\":\"a\"}"},
+		Response:   &StubResponse{Type: "success", Content: "{}"},
+	}
+	store.Add(partial)
+	store.Add(exact)
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+
+	assert.Equal(t, exact, matcher.Match(context.Background(), "method1", "{\"name\":\"a\"}"))
+}
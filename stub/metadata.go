@@ -0,0 +1,147 @@
+package stub
+
+import (
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MetadataMatcher matches the values of a metadata key of a call. Keys are case-insensitive.
+// When several conditions are set all of them must hold.
+type MetadataMatcher struct {
+	Equals   []string `json:"equals,omitempty"`   // the key has exactly these values, in any order
+	Contains string   `json:"contains,omitempty"` // one of the values of the key is this one. Other values are allowed.
+	Regex    string   `json:"regex,omitempty"`    // one of the values of the key matches the regular expression
+	Present  bool     `json:"present,omitempty"`  // the key is sent, with any value
+	Absent   bool     `json:"absent,omitempty"`   // the key isn't sent
+}
+
+// matches tells whether the values of the key in the call satisfy the matcher
+func (m MetadataMatcher) matches(values []string) bool {
+	if m.Absent {
+		return len(values) == 0
+	}
+	if m.Present && len(values) == 0 {
+		return false
+	}
+	if len(m.Equals) > 0 && !equalValues(m.Equals, values) {
+		return false
+	}
+	if m.Contains != "" && !containsValue(values, func(value string) bool { return value == m.Contains }) {
+		return false
+	}
+	if m.Regex != "" {
+		regex, err := compileRegex(m.Regex)
+		if err != nil || !containsValue(values, regex.MatchString) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MetadataMatcher) String() string {
+	conditions := make([]string, 0)
+	if len(m.Equals) > 0 {
+		conditions = append(conditions, fmt.Sprintf("equal to %v", m.Equals))
+	}
+	if m.Contains != "" {
+		conditions = append(conditions, fmt.Sprintf("containing %s", m.Contains))
+	}
+	if m.Regex != "" {
+		conditions = append(conditions, fmt.Sprintf("matching %s", m.Regex))
+	}
+	if m.Present {
+		conditions = append(conditions, "present")
+	}
+	if m.Absent {
+		conditions = append(conditions, "absent")
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// isValid checks the matcher has at least one condition and its conditions can hold together
+func (m MetadataMatcher) isValid(key string) (errMsgs []string) {
+	if len(m.Equals) == 0 && m.Contains == "" && m.Regex == "" && !m.Present && !m.Absent {
+		errMsgs = append(errMsgs, fmt.Sprintf("Metadata matcher of key '%s' must have at least one of 'equals', 'contains', 'regex', 'present' or 'absent'.", key))
+	}
+	if m.Absent && (len(m.Equals) > 0 || m.Contains != "" || m.Regex != "" || m.Present) {
+		errMsgs = append(errMsgs, fmt.Sprintf("Metadata matcher of key '%s' can't combine 'absent' with other conditions.", key))
+	}
+	if _, err := compileRegex(m.Regex); err != nil {
+		errMsgs = append(errMsgs, fmt.Sprintf("Metadata matcher of key '%s' has an invalid regular expression: %s", key, err))
+	}
+	return errMsgs
+}
+
+func equalValues(expected, values []string) bool {
+	expected = append([]string(nil), expected...)
+	values = append([]string(nil), values...)
+	sort.Strings(expected)
+	sort.Strings(values)
+	return strings.Join(expected, ",") == strings.Join(values, ",")
+}
+
+func containsValue(values []string, matches func(string) bool) bool {
+	for _, value := range values {
+		if matches(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMetadataMatchers returns the matchers of the request with their keys in lower case, like the keys of metadata.MD
+func getMetadataMatchers(request *StubRequest) map[string]MetadataMatcher {
+	matchers := make(map[string]MetadataMatcher, len(request.MetadataMatch))
+	for key, matcher := range request.MetadataMatch {
+		matchers[strings.ToLower(key)] = matcher
+	}
+	return matchers
+}
+
+// matchMetadataMatchers tells whether every matcher of the request holds for the metadata
+func matchMetadataMatchers(request *StubRequest, md metadata.MD) bool {
+	for key, matcher := range getMetadataMatchers(request) {
+		if !matcher.matches(md.Get(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidMetadataMatch checks the metadata matchers of the request
+func (s StubRequest) isValidMetadataMatch() (errMsgs []string) {
+	keys := make([]string, 0, len(s.MetadataMatch))
+	for key := range s.MetadataMatch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		errMsgs = append(errMsgs, s.MetadataMatch[key].isValid(key)...)
+	}
+	return errMsgs
+}
+
+// metadataKeyRegex matches the keys gRPC accepts in metadata
+var metadataKeyRegex = regexp.MustCompile("^[0-9a-zA-Z_.-]+$")
+
+// isValidResponseMetadata checks the keys of headers, trailers or other metadata sent can be sent by gRPC.
+// Keys starting with 'grpc-' are reserved for gRPC itself.
+func isValidResponseMetadata(values map[string][]string, name string) (errMsgs []string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !metadataKeyRegex.MatchString(key) {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s '%s' is not a valid metadata key.", name, key))
+		}
+		if strings.HasPrefix(strings.ToLower(key), "grpc-") {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s '%s' is reserved for gRPC.", name, key))
+		}
+	}
+	return errMsgs
+}
@@ -0,0 +1,92 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestMetadataMatcher_matches(t *testing.T) {
+	tests := []struct {
+		matcher MetadataMatcher
+		values  []string
+		matches bool
+	}{
+		{MetadataMatcher{Equals: []string{"b", "a"}}, []string{"a", "b"}, true},
+		{MetadataMatcher{Equals: []string{"a"}}, []string{"a", "b"}, false},
+		{MetadataMatcher{Contains: "a"}, []string{"proxy", "a"}, true},
+		{MetadataMatcher{Contains: "a"}, []string{"b"}, false},
+		{MetadataMatcher{Regex: "^Bearer "}, []string{"Bearer abc"}, true},
+		{MetadataMatcher{Regex: "^Bearer "}, []string{"Basic abc"}, false},
+		{MetadataMatcher{Present: true}, []string{""}, true},
+		{MetadataMatcher{Present: true}, nil, false},
+		{MetadataMatcher{Absent: true}, nil, true},
+		{MetadataMatcher{Absent: true}, []string{"a"}, false},
+		{MetadataMatcher{Present: true, Regex: "^v"}, []string{"v1"}, true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, test.matcher.matches(test.values), "%s %v", test.matcher, test.values)
+	}
+}
+
+func TestStubRequest_isValidMetadataMatch(t *testing.T) {
+	request := StubRequest{MetadataMatch: map[string]MetadataMatcher{
+		"authorization": {Regex: "^Bearer ("},
+		"x-debug":       {Absent: true, Present: true},
+		"x-empty":       {},
+		"x-tenant":      {Contains: "acme"},
+	}}
+	assert.Equal(t, []string{
+		"Metadata matcher of key 'authorization' has an invalid regular expression: error parsing regexp: missing closing ): `^Bearer (`",
+		"Metadata matcher of key 'x-debug' can't combine 'absent' with other conditions.",
+		"Metadata matcher of key 'x-empty' must have at least one of 'equals', 'contains', 'regex', 'present' or 'absent'.",
+	}, request.isValidMetadataMatch())
+}
+
+func TestStubsMatcher_Match_MetadataMatch(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	matcher := NewStubsMatcher(store, NewInMemoryScenariosStore())
+	for _, match := range []string{"exact", "partial"} {
+		store.Add(&Stub{
+			FullMethod: "/svc/" + match,
+			Type:       "mock",
+			Request: &StubRequest{Match: match, Content: `{"name":"a"}`, MetadataMatch: map[string]MetadataMatcher{
+				"Authorization": {Regex: "^Bearer "},
+				"X-Tenant":      {Contains: "acme"},
+				"x-debug":       {Absent: true},
+			}},
+			Response: &StubResponse{Type: "success", Content: `{}`},
+		})
+	}
+
+	for _, match := range []string{"exact", "partial"} {
+		fullMethod := "/svc/" + match
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer abc", "x-tenant", "proxy", "x-tenant", "acme"))
+		assert.NotNil(t, matcher.Match(ctx, fullMethod, `{"name":"a"}`), match)
+
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer abc", "x-tenant", "acme", "x-debug", "1"))
+		assert.Nil(t, matcher.Match(ctx, fullMethod, `{"name":"a"}`), match)
+
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic abc", "x-tenant", "acme"))
+		assert.Nil(t, matcher.Match(ctx, fullMethod, `{"name":"a"}`), match)
+		assert.Nil(t, matcher.Match(context.Background(), fullMethod, `{"name":"a"}`), match)
+	}
+
+	nearMisses := matcher.NearMisses(metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "acme")), "/svc/exact", `{"name":"a"}`)
+	assert.Equal(t, []string{"metadata authorization: expected matching ^Bearer  but got []"}, nearMisses[0].Differences)
+}
+
+func TestMatchRequestMetadata_CaseInsensitiveKeys(t *testing.T) {
+	request := &StubRequest{Metadata: map[string][]string{"X-Tenant": {"acme"}}}
+	assert.True(t, matchRequestMetadata(request, metadata.Pairs("x-tenant", "acme")))
+	assert.False(t, matchRequestMetadata(request, metadata.Pairs("x-tenant", "other")))
+}
+
+func TestIsValidResponseMetadata(t *testing.T) {
+	headers := map[string][]string{"x-rate-limit": {"10"}, "Grpc-Status": {"0"}, "bad key": {"a"}}
+	assert.Equal(t, []string{
+		"Response header 'Grpc-Status' is reserved for gRPC.",
+		"Response header 'bad key' is not a valid metadata key.",
+	}, isValidResponseMetadata(headers, "Response header"))
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"reflect"
+	"sync/atomic"
 )
 
 type JsonString string
@@ -34,17 +36,60 @@ func (j *StubType) UnmarshalJSON(data []byte) error {
 }
 
 type Stub struct {
-	FullMethod string        `json:"fullMethod"`
-	Type       StubType      `json:"type"`     // mock | forward - default to mock to maintain backwards compatibility
-	Request    *StubRequest  `json:"request"`  // Always required
-	Response   *StubResponse `json:"response"` // required if type = mock. Ignored otherwise.
-	Forward    *StubForward  `json:"forward"`  // required if type = forward. Ignored otherwise.
+	FullMethod    string        `json:"fullMethod"`
+	Type          StubType      `json:"type"`                    // mock | forward - default to mock to maintain backwards compatibility
+	Request       *StubRequest  `json:"request"`                 // Always required
+	Response      *StubResponse `json:"response"`                // required if type = mock. Ignored otherwise.
+	Forward       *StubForward  `json:"forward"`                 // required if type = forward. Ignored otherwise.
+	Scenario      string        `json:"scenario,omitempty"`      // name of the scenario the stub belongs to. Optional.
+	RequiredState string        `json:"requiredState,omitempty"` // state the scenario must be in for the stub to match. Matches in any state if empty.
+	NewState      string        `json:"newState,omitempty"`      // state the scenario moves to after the stub matches. Optional.
+	Priority      int           `json:"priority,omitempty"`      // stubs with higher priority win when several match the same request. Defaults to 0.
+	Fault         *Fault        `json:"fault,omitempty"`         // faults injected in the calls the stub matches. Optional.
+	// order in which the stub was added to the store, used to break ties between stubs with the same priority
+	added uint64
+}
+
+// key identifies the stub in the stores. Stubs in a scenario are also identified by the state they require
+// so that the same request can have a different response in each state.
+// Key identifies the stub among the stubs of its method. The store holds a single stub per key.
+func (stub *Stub) Key() string {
+	if stub.Scenario == "" {
+		return stub.Request.String()
+	}
+	return fmt.Sprintf("%s [%s:%s]", stub.Request.String(), stub.Scenario, stub.RequiredState)
 }
 
 type StubRequest struct {
-	Match    string              `json:"match"` // exact | partial
-	Content  JsonString          `json:"content"`
-	Metadata map[string][]string `json:"metadata"`
+	Match         string                     `json:"match"` // exact | partial | jsonpath | cel
+	Content       JsonString                 `json:"content"`
+	Paths         map[string]interface{}     `json:"paths,omitempty"`      // JSON path -> expected value or operators. Required when match = jsonpath.
+	Expression    string                     `json:"expression,omitempty"` // CEL expression on the request and metadata. Required when match = cel.
+	Metadata      map[string][]string        `json:"metadata"`
+	MetadataMatch map[string]MetadataMatcher `json:"metadataMatch,omitempty"` // key -> matcher of its values. Applied together with Metadata.
+	Stream        []JsonString               `json:"stream,omitempty"`        // request messages of a client streaming call. Each one is compared using Match.
+	StreamMatch   string                     `json:"streamMatch,omitempty"`   // all | last | any - required when Stream is provided
+}
+
+// matches tells whether the request JSON matches the content or, when match = jsonpath or cel, the paths or the expression
+func (s StubRequest) matches(fullMethod, requestJson string, md metadata.MD) bool {
+	switch s.Match {
+	case "jsonpath":
+		return matchPaths(s.Paths, requestJson)
+	case "cel":
+		return matchCel(s.Expression, fullMethod, requestJson, md)
+	}
+	return matchContent(s.Match, s.Content, requestJson)
+}
+
+// usesContent tells whether the request is matched on its content, which isn't the case of jsonpath and cel
+func (s StubRequest) usesContent() bool {
+	return s.Match != "jsonpath" && s.Match != "cel"
+}
+
+// IsStream tells whether the request describes the sequence of messages of a client streaming call
+func (s StubRequest) IsStream() bool {
+	return len(s.Stream) > 0
 }
 
 func (s StubRequest) String() string {
@@ -53,14 +98,78 @@ func (s StubRequest) String() string {
 }
 
 type StubResponse struct {
-	Type    string         `json:"type"` // success | error
-	Content JsonString     `json:"content"`
-	Error   *ErrorResponse `json:"error"`
+	Type         string              `json:"type"` // success | error | stream | conversation | sequence
+	Content      JsonString          `json:"content"`
+	Error        *ErrorResponse      `json:"error"`                  // when type = stream it is the optional status the stream is closed with
+	Stream       []StreamMessage     `json:"stream,omitempty"`       // messages sent in order when type = stream
+	Conversation []ConversationStep  `json:"conversation,omitempty"` // script run against a bidirectional stream when type = conversation
+	Sequence     *ResponseSequence   `json:"sequence,omitempty"`     // responses served one per match when type = sequence
+	Delay        *ResponseDelay      `json:"delay,omitempty"`        // time to wait before replying. Optional.
+	Template     bool                `json:"template,omitempty"`     // renders the contents as templates of the call. See renderTemplate.
+	Headers      map[string][]string `json:"headers,omitempty"`      // metadata sent as the response headers. Optional.
+	Trailers     map[string][]string `json:"trailers,omitempty"`     // metadata sent as the response trailers. Optional.
 }
 
+// ResponseSequence serves the next of its responses each time the stub matches
+type ResponseSequence struct {
+	served    uint64          // number of responses served so far. Must be the first field to be 64-bit aligned for atomic operations.
+	Responses []*StubResponse `json:"responses"`           // 'success' or 'error' responses
+	Exhausted string          `json:"exhausted,omitempty"` // repeat | cycle | error - what to serve after the last response. Defaults to repeat.
+	Error     *ErrorResponse  `json:"error,omitempty"`     // status returned after the last response when exhausted = error
+}
+
+// next returns the response to serve for the current match of the stub
+func (s *ResponseSequence) next() *StubResponse {
+	n := atomic.AddUint64(&s.served, 1) - 1
+	count := uint64(len(s.Responses))
+	if n < count {
+		return s.Responses[n]
+	}
+	switch s.Exhausted {
+	case "cycle":
+		return s.Responses[n%count]
+	case "error":
+		return &StubResponse{Type: "error", Error: s.Error}
+	}
+	return s.Responses[count-1]
+}
+
+type StreamMessage struct {
+	Content JsonString `json:"content"`
+	Delay   uint32     `json:"delay,omitempty"` // milliseconds to wait before sending the message
+}
+
+// ConversationStep is one step of the script of a bidirectional streaming stub. Expect steps run in order as the client
+// messages arrive while send steps run in order from the start of the stream:
+// - expect: waits for the next message from the client, which must match Content, then sends the messages in Send
+// - send: sends the messages in Send unprompted, each after its delay, without waiting for the client
+// - close: once the expect steps are done and the unprompted messages sent, ends the stream with the status in Error, or OK when there is no error
+type ConversationStep struct {
+	Type    string          `json:"type"`              // expect | send | close
+	Match   string          `json:"match,omitempty"`   // exact | partial - required when type = expect
+	Content JsonString      `json:"content,omitempty"` // message expected from the client when type = expect
+	Send    []StreamMessage `json:"send,omitempty"`    // messages sent in order when type = expect or send
+	Error   *ErrorResponse  `json:"error,omitempty"`   // status the stream is closed with when type = close
+}
+
+// Matches tells whether the message received from the client is the one expected by the step
+func (step ConversationStep) Matches(requestJson string) bool {
+	return matchContent(step.Match, step.Content, requestJson)
+}
+
+// StubForward is the server the calls are forwarded to. Only unary calls can be forwarded: the streaming calls matched by
+// a 'forward' stub end with the UNIMPLEMENTED status, and the streaming calls no stub matches aren't sent to the upstreams.
 type StubForward struct {
-	ServerAddress string `json:"serverAddress"`
-	Record        bool   `json:"record"`
+	ServerAddress string              `json:"serverAddress"`
+	Record        bool                `json:"record"`
+	TLS           *ForwardTLS         `json:"tls,omitempty"`         // connects to the server with TLS. Plaintext when not set.
+	Credentials   *ForwardCredentials `json:"credentials,omitempty"` // credentials sent on every call forwarded. Optional.
+	Metadata      *ForwardMetadata    `json:"metadata,omitempty"`    // metadata of the call sent to the server. All of it when not set.
+	Timeout       uint32              `json:"timeout,omitempty"`     // milliseconds the server has to reply, capped by the deadline of the call. Optional.
+}
+
+func (stub *Stub) isConversation() bool {
+	return stub.Type == "mock" && stub.Response != nil && stub.Response.Type == "conversation"
 }
 
 type ErrorResponse struct {
@@ -88,6 +197,8 @@ func (j JsonString) String() string {
 	return string(j)
 }
 
+// UnmarshalJSON reads the JSON as it is. JSON strings are kept quoted, like the contents of messages
+// such as google.protobuf.Duration. Response templates given as JSON strings are read as their text by templateText.
 func (j *JsonString) UnmarshalJSON(data []byte) error {
 	buffer := new(bytes.Buffer)
 	err := json.Compact(buffer, data)
@@ -104,9 +215,22 @@ func (j *JsonString) MarshalJSON() ([]byte, error) {
 	if val == "" {
 		return []byte("{}"), nil
 	}
+	if !json.Valid([]byte(val)) {
+		return json.Marshal(val)
+	}
 	return []byte(val), nil
 }
 
+// templateText returns the text of a response template. Templates usually aren't valid JSON, so they can be
+// provided as a JSON string whose text is the template.
+func (j JsonString) templateText() string {
+	var text string
+	if err := json.Unmarshal([]byte(j), &text); err == nil {
+		return text
+	}
+	return string(j)
+}
+
 func (j *JsonString) Matches(other JsonString) bool {
 	jsonMap := new(map[string]interface{})
 	otherJsonMap := new(map[string]interface{})
@@ -124,11 +248,21 @@ func (j *JsonString) Equals(other JsonString) bool {
 }
 
 func jsonStringMatches(jsonMap, otherJsonMap map[string]interface{}, mustBeEqual bool) bool {
-	if mustBeEqual && len(jsonMap) != len(otherJsonMap) {
-		return false
+	if mustBeEqual {
+		for key := range otherJsonMap {
+			if _, found := jsonMap[key]; !found {
+				return false
+			}
+		}
 	}
 	for key, value := range jsonMap {
 		otherValue, found := otherJsonMap[key]
+		if operators, ok := asOperators(value); ok {
+			if !matchOperators(operators, otherValue, found, mustBeEqual) {
+				return false
+			}
+			continue
+		}
 		if !found {
 			return false
 		}
@@ -144,40 +278,9 @@ func jsonStringMatches(jsonMap, otherJsonMap map[string]interface{}, mustBeEqual
 			}
 			continue
 		case "[]interface {}": // repeated object
-			// naive implementation of comparison of repeated messages.
-			// TODO investigate a more performant way to compare
-			items := jsonMap[key].([]interface{})
-			otherItems := otherJsonMap[key].([]interface{})
-			if len(items) != len(otherItems) {
+			if !matchItems(jsonMap[key].([]interface{}), otherJsonMap[key].([]interface{}), mustBeEqual) {
 				return false
 			}
-			for _, item := range items {
-				var found = false
-				for _, otherItem := range otherItems {
-					itemType := fmt.Sprintf("%T", item)
-					otherItemType := fmt.Sprintf("%T", otherItem)
-					if itemType != otherItemType {
-						// Not sure if they can be different
-						continue
-					}
-					switch itemType {
-					case "map[string]interface {}":
-						if jsonStringMatches(item.(map[string]interface{}), otherItem.(map[string]interface{}), mustBeEqual) {
-							found = true
-							break
-						}
-					default:
-						if item == otherItem {
-							found = true
-							break
-						}
-					}
-
-				}
-				if !found {
-					return false
-				}
-			}
 			continue
 		}
 		if value != otherValue {
@@ -187,6 +290,28 @@ func jsonStringMatches(jsonMap, otherJsonMap map[string]interface{}, mustBeEqual
 	return true
 }
 
+// matchItems tells whether every item of a repeated field in a stub request matches an item of the request, in any order
+func matchItems(items, otherItems []interface{}, mustBeEqual bool) bool {
+	// naive implementation of comparison of repeated messages.
+	// TODO investigate a more performant way to compare
+	if len(items) != len(otherItems) {
+		return false
+	}
+	for _, item := range items {
+		var found = false
+		for _, otherItem := range otherItems {
+			if matchValue(item, otherItem, mustBeEqual) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 type InvalidStubResponse struct {
 	Errors  []string `json:"errors"`
 	Example Stub     `json:"example"`
@@ -1,8 +1,15 @@
 package stub
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	"testing"
+	"time"
 )
 
 func TestJsonString_Matches_TwoEqualJsonStrings(t *testing.T) {
@@ -40,3 +47,35 @@ func TestJsonString_Equals_RightJsonStringContainsAllFieldsOfLeftJsonString(t *t
 	str2 := JsonString("{\"field1\":{\"subfieldd1\":\"value1\", \"subfield2\": 2}}")
 	assert.False(t, str1.Equals(str2))
 }
+
+func TestStub_MarshalRoundTrip_WellKnownTypes(t *testing.T) {
+	tests := []struct {
+		content  JsonString
+		response proto.Message
+		expected proto.Message
+	}{
+		{`"1.500s"`, new(durationpb.Duration), durationpb.New(1500 * time.Millisecond)},
+		{`"2021-01-01T10:00:00Z"`, new(timestamppb.Timestamp), timestamppb.New(time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC))},
+		{`"text"`, new(wrapperspb.StringValue), wrapperspb.String("text")},
+		{`5`, new(wrapperspb.Int32Value), wrapperspb.Int32(5)},
+		{`{"name":"method1"}`, new(apipb.Method), &apipb.Method{Name: "method1"}},
+	}
+	for _, test := range tests {
+		s := &Stub{
+			FullMethod: "method1",
+			Type:       "mock",
+			Request:    &StubRequest{Match: "exact", Content: `{}`},
+			Response:   &StubResponse{Type: "success", Content: test.content},
+		}
+		data, err := json.Marshal(s)
+		assert.Nil(t, err)
+		loaded := new(Stub)
+		assert.Nil(t, json.Unmarshal(data, loaded))
+		assert.Equal(t, test.content, loaded.Response.Content)
+
+		response, err := jsonToResponse(loaded.Response.Content.String(), test.response)
+		if assert.Nil(t, err, string(test.content)) {
+			assert.True(t, proto.Equal(test.expected, response.(proto.Message)), string(test.content))
+		}
+	}
+}
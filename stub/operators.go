@@ -0,0 +1,360 @@
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operators replace the value of a field in the content of a stub request to match it with something other than equality.
+// They are JSON objects whose keys start with '$', which can't collide with proto field names. For example:
+//
+//	{"name": {"$prefix": "jo", "$ignoreCase": true}, "age": {"$between": [18, 65]}, "nickname": {"$absent": true}}
+//
+// When an object has several operators all of them must match.
+//
+// - $eq: the value is equal to the operand
+// - $regex: the string matches the regular expression
+// - $prefix | $suffix | $contains: the string starts with, ends with or contains the operand
+// - $ignoreCase: true makes $eq, $regex, $prefix, $suffix and $contains case-insensitive
+// - $gt | $gte | $lt | $lte: the number is greater than, greater or equal, less than or less or equal to the operand
+// - $between: the number is within the [min, max] operand, both inclusive
+// - $any: true matches any value as long as the field is present
+// - $absent: true matches when the field is not present. Fields with their default value are never present in a request.
+// - $arrayContains: at least one element of the array matches the operand
+// - $anyOrder: the array has the elements of the operand in any order
+// - $inOrder: the array has the elements of the operand in the same order
+const operatorPrefix = "$"
+
+// maxCachedRegexps is the number of regular expressions of $regex operators kept compiled
+const maxCachedRegexps = 1000
+
+// regexps caches the regular expressions already compiled since the same stubs are evaluated on every call
+var regexps = newLRUCache(maxCachedRegexps)
+
+type compiledRegex struct {
+	regex *regexp.Regexp
+	err   error
+}
+
+// compileRegex compiles the pattern, or returns it from the cache when it was already compiled
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexps.get(pattern); ok {
+		compiled := cached.(compiledRegex)
+		return compiled.regex, compiled.err
+	}
+	regex, err := regexp.Compile(pattern)
+	regexps.add(pattern, compiledRegex{regex: regex, err: err})
+	return regex, err
+}
+
+// asOperators returns the operators when the value of a field in a stub request is an object of operators
+func asOperators(value interface{}) (map[string]interface{}, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		return nil, false
+	}
+	for key := range object {
+		if !strings.HasPrefix(key, operatorPrefix) {
+			return nil, false
+		}
+	}
+	return object, true
+}
+
+// HasOperators tells whether any field in the JSON uses operators
+func (j JsonString) HasOperators() bool {
+	var content interface{}
+	if err := json.Unmarshal([]byte(j), &content); err != nil {
+		return false
+	}
+	return hasOperators(content)
+}
+
+func hasOperators(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range typedValue {
+			if strings.HasPrefix(key, operatorPrefix) || hasOperators(fieldValue) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range typedValue {
+			if hasOperators(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchOperators tells whether the value of a field in the request, if found, satisfies all the operators
+func matchOperators(operators map[string]interface{}, value interface{}, found bool, mustBeEqual bool) bool {
+	if operators["$absent"] == true {
+		return !found
+	}
+	if !found {
+		return false
+	}
+	ignoreCase := operators["$ignoreCase"] == true
+	for name, operand := range operators {
+		if !matchOperator(name, operand, value, ignoreCase, mustBeEqual) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchOperator(name string, operand, value interface{}, ignoreCase, mustBeEqual bool) bool {
+	switch name {
+	case "$any", "$absent", "$ignoreCase":
+		return true
+	case "$eq":
+		expected, isString := operand.(string)
+		if isString && ignoreCase {
+			actual, ok := value.(string)
+			return ok && strings.EqualFold(expected, actual)
+		}
+		return matchValue(operand, value, mustBeEqual)
+	case "$regex":
+		pattern, ok := operand.(string)
+		actual, isString := value.(string)
+		if !ok || !isString {
+			return false
+		}
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		regex, err := compileRegex(pattern)
+		return err == nil && regex.MatchString(actual)
+	case "$prefix", "$suffix", "$contains":
+		expected, ok := operand.(string)
+		actual, isString := value.(string)
+		if !ok || !isString {
+			return false
+		}
+		if ignoreCase {
+			expected, actual = strings.ToLower(expected), strings.ToLower(actual)
+		}
+		switch name {
+		case "$prefix":
+			return strings.HasPrefix(actual, expected)
+		case "$suffix":
+			return strings.HasSuffix(actual, expected)
+		}
+		return strings.Contains(actual, expected)
+	case "$gt", "$gte", "$lt", "$lte":
+		expected, ok := toNumber(operand)
+		actual, isNumber := toNumber(value)
+		if !ok || !isNumber {
+			return false
+		}
+		switch name {
+		case "$gt":
+			return actual > expected
+		case "$gte":
+			return actual >= expected
+		case "$lt":
+			return actual < expected
+		}
+		return actual <= expected
+	case "$between":
+		min, max, ok := toRange(operand)
+		actual, isNumber := toNumber(value)
+		return ok && isNumber && actual >= min && actual <= max
+	case "$arrayContains":
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if matchValue(operand, item, mustBeEqual) {
+				return true
+			}
+		}
+		return false
+	case "$anyOrder":
+		expected, ok := operand.([]interface{})
+		items, isArray := value.([]interface{})
+		if !ok || !isArray || len(expected) != len(items) {
+			return false
+		}
+		used := make([]bool, len(items))
+		for _, expectedItem := range expected {
+			found := false
+			for i, item := range items {
+				if !used[i] && matchValue(expectedItem, item, mustBeEqual) {
+					used[i], found = true, true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case "$inOrder":
+		expected, ok := operand.([]interface{})
+		items, isArray := value.([]interface{})
+		if !ok || !isArray || len(expected) != len(items) {
+			return false
+		}
+		for i, expectedItem := range expected {
+			if !matchValue(expectedItem, items[i], mustBeEqual) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchValue compares a value in a stub request, which can be an object of operators, with a value in the request
+func matchValue(expected, value interface{}, mustBeEqual bool) bool {
+	if operators, ok := asOperators(expected); ok {
+		return matchOperators(operators, value, true, mustBeEqual)
+	}
+	switch typedExpected := expected.(type) {
+	case map[string]interface{}:
+		object, ok := value.(map[string]interface{})
+		return ok && jsonStringMatches(typedExpected, object, mustBeEqual)
+	case []interface{}:
+		items, ok := value.([]interface{})
+		return ok && matchItems(typedExpected, items, mustBeEqual)
+	}
+	return expected == value
+}
+
+// toNumber reads a JSON number. 64 bits integers are strings in the JSON of proto messages.
+func toNumber(value interface{}) (float64, bool) {
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, true
+	case string:
+		number, err := strconv.ParseFloat(typedValue, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func toRange(operand interface{}) (min, max float64, ok bool) {
+	bounds, isArray := operand.([]interface{})
+	if !isArray || len(bounds) != 2 {
+		return 0, 0, false
+	}
+	min, minOk := toNumber(bounds[0])
+	max, maxOk := toNumber(bounds[1])
+	return min, max, minOk && maxOk && min <= max
+}
+
+// validateOperators checks the operators used on a field are known and can be applied to its type.
+// The type isn't checked when the field is nil.
+func validateOperators(field protoreflect.FieldDescriptor, operators map[string]interface{}, fieldName string) (errorMessages []string) {
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		operand := operators[name]
+		switch name {
+		case "$any", "$absent", "$ignoreCase":
+			if _, ok := operand.(bool); !ok {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' expects a boolean.", name, fieldName))
+			}
+		case "$eq":
+		case "$regex", "$prefix", "$suffix", "$contains":
+			if field != nil && !isStringField(field) {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' can't be used on field '%s', which is not a string.", name, fieldName))
+			}
+			if _, ok := operand.(string); !ok {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' expects a string.", name, fieldName))
+			}
+		case "$gt", "$gte", "$lt", "$lte", "$between":
+			if field != nil && !isNumberField(field) {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' can't be used on field '%s', which is not a number.", name, fieldName))
+			}
+			if _, ok := toNumber(operand); name != "$between" && !ok {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' expects a number.", name, fieldName))
+			}
+			if _, _, ok := toRange(operand); name == "$between" && !ok {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' expects an array with the minimum and maximum numbers.", name, fieldName))
+			}
+		case "$arrayContains", "$anyOrder", "$inOrder":
+			if field != nil && !field.IsList() {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' can't be used on field '%s', which is not repeated.", name, fieldName))
+			}
+			if _, ok := operand.([]interface{}); name != "$arrayContains" && !ok {
+				errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' expects an array.", name, fieldName))
+			}
+		default:
+			errorMessages = append(errorMessages, fmt.Sprintf("Operator '%s' of field '%s' does not exist.", name, fieldName))
+		}
+	}
+	return errorMessages
+}
+
+// validateRegexps checks the patterns of the $regex operators anywhere in the JSON content compile.
+// Stubs are checked with it before the operators are validated against the fields they are used on.
+func (j JsonString) validateRegexps(baseName string) (errorMessages []string) {
+	var content interface{}
+	if err := json.Unmarshal([]byte(j), &content); err != nil {
+		return nil
+	}
+	return validateRegexps(content, baseName)
+}
+
+func validateRegexps(value interface{}, fieldName string) (errorMessages []string) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if operators, ok := asOperators(typedValue); ok {
+			if pattern, ok := operators["$regex"].(string); ok {
+				if _, err := compileRegex(pattern); err != nil {
+					errorMessages = append(errorMessages, fmt.Sprintf("Operator '$regex' of field '%s' has an invalid regular expression: %s", fieldName, err))
+				}
+			}
+			for _, name := range []string{"$eq", "$arrayContains", "$anyOrder", "$inOrder"} {
+				errorMessages = append(errorMessages, validateRegexps(operators[name], fieldName)...)
+			}
+			return errorMessages
+		}
+		for _, key := range sortedKeys(typedValue) {
+			errorMessages = append(errorMessages, validateRegexps(typedValue[key], fieldName+"."+key)...)
+		}
+	case []interface{}:
+		for i, item := range typedValue {
+			errorMessages = append(errorMessages, validateRegexps(item, fmt.Sprintf("%s[%d]", fieldName, i))...)
+		}
+	}
+	return errorMessages
+}
+
+func isStringField(field protoreflect.FieldDescriptor) bool {
+	if field.IsList() || field.IsMap() {
+		return false
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.EnumKind:
+		return true
+	}
+	return false
+}
+
+func isNumberField(field protoreflect.FieldDescriptor) bool {
+	if field.IsList() || field.IsMap() {
+		return false
+	}
+	switch field.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Fixed32Kind, protoreflect.Sfixed64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return true
+	}
+	return false
+}
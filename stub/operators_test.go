@@ -0,0 +1,122 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/typepb"
+	"testing"
+)
+
+func TestJsonString_Matches_Operators(t *testing.T) {
+	request := JsonString(`{"name":"John Smith","age":42,"id":"9007199254740993","tags":["a","b","c"],"items":[{"sku":"x1","qty":2}]}`)
+	tests := []struct {
+		stub    JsonString
+		matches bool
+	}{
+		{`{"name":{"$regex":"^J.* Smith$"}}`, true},
+		{`{"name":{"$regex":"^smith"}}`, false},
+		{`{"name":{"$prefix":"john","$ignoreCase":true}}`, true},
+		{`{"name":{"$prefix":"john"}}`, false},
+		{`{"name":{"$suffix":"Smith"}}`, true},
+		{`{"name":{"$contains":"n S"}}`, true},
+		{`{"name":{"$eq":"JOHN SMITH","$ignoreCase":true}}`, true},
+		{`{"age":{"$gt":40,"$lt":50}}`, true},
+		{`{"age":{"$gte":42,"$lte":42}}`, true},
+		{`{"age":{"$gt":42}}`, false},
+		{`{"age":{"$between":[18,65]}}`, true},
+		{`{"id":{"$gt":9007199254740000}}`, true},
+		{`{"age":{"$any":true}}`, true},
+		{`{"nickname":{"$any":true}}`, false},
+		{`{"nickname":{"$absent":true}}`, true},
+		{`{"name":{"$absent":true}}`, false},
+		{`{"tags":{"$arrayContains":"b"}}`, true},
+		{`{"tags":{"$arrayContains":"d"}}`, false},
+		{`{"items":{"$arrayContains":{"sku":{"$prefix":"x"}}}}`, true},
+		{`{"tags":{"$anyOrder":["c","a","b"]}}`, true},
+		{`{"tags":{"$inOrder":["c","a","b"]}}`, false},
+		{`{"tags":{"$inOrder":["a","b",{"$any":true}]}}`, true},
+		{`{"tags":[{"$prefix":"c"},"a","b"]}`, true},
+	}
+	for _, test := range tests {
+		content := test.stub
+		assert.Equal(t, test.matches, content.Matches(request), string(content))
+	}
+}
+
+func TestJsonString_Equals_Operators(t *testing.T) {
+	request := JsonString(`{"name":"John","age":42}`)
+
+	withAllFields := JsonString(`{"name":{"$prefix":"J"},"age":{"$any":true},"nickname":{"$absent":true}}`)
+	withMissingField := JsonString(`{"name":{"$prefix":"J"}}`)
+	assert.True(t, withAllFields.Equals(request))
+	assert.False(t, withMissingField.Equals(request))
+}
+
+func TestJsonString_HasOperators(t *testing.T) {
+	assert.True(t, JsonString(`{"items":[{"sku":{"$prefix":"x"}}]}`).HasOperators())
+	assert.False(t, JsonString(`{"items":[{"sku":"x"}]}`).HasOperators())
+}
+
+func TestIsStubValid_Operators(t *testing.T) {
+	field := (&typepb.Field{}).ProtoReflect().Descriptor()
+	s := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request: &StubRequest{
+			Match:   "partial",
+			Content: `{"name":{"$prefix":"a","$ignoreCase":true},"number":{"$between":[1,10]},"options":{"$arrayContains":{"name":"x"}},"jsonName":{"$absent":true}}`,
+		},
+		Response: &StubResponse{Type: "success", Content: "{}"},
+	}
+	isValid, errorMessages := IsStubValid(s, field, field)
+	assert.True(t, isValid, errorMessages)
+
+	s.Request.Content = `{"name":{"$gt":1},"number":{"$regex":"^1"},"options":{"$inOrder":"x"},"packed":{"$unknown":true}}`
+	isValid, errorMessages = IsStubValid(s, field, field)
+	assert.False(t, isValid)
+	assert.ElementsMatch(t, []string{
+		"Operator '$gt' can't be used on field 'request.content.name', which is not a number.",
+		"Operator '$regex' can't be used on field 'request.content.number', which is not a string.",
+		"Operator '$inOrder' of field 'request.content.options' expects an array.",
+		"Operator '$unknown' of field 'request.content.packed' does not exist.",
+	}, errorMessages)
+
+	s.Request.Content = `{"name":{"$regex":"("}}`
+	isValid, errorMessages = IsStubValid(s, field, field)
+	assert.False(t, isValid)
+	assert.Equal(t, []string{
+		"Operator '$regex' of field 'request.content.name' has an invalid regular expression: error parsing regexp: missing closing ): `(`",
+	}, errorMessages)
+}
+
+func TestStub_IsValid_Regexps(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request: &StubRequest{
+			Match:   "partial",
+			Content: `{"user":{"name":{"$regex":"^(a"}},"tags":{"$arrayContains":{"label":{"$regex":"[z"}}}}`,
+			Stream:  []JsonString{`{"name":{"$regex":"^a"}}`, `{"items":[{"name":{"$regex":"*"}}]}`},
+		},
+		Response: &StubResponse{Type: "success", Content: "{}"},
+	}
+	s.Request.StreamMatch = "all"
+	isValid, errorMessages := s.IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{
+		"Operator '$regex' of field 'request.content.tags.label' has an invalid regular expression: error parsing regexp: missing closing ]: `[z`",
+		"Operator '$regex' of field 'request.content.user.name' has an invalid regular expression: error parsing regexp: missing closing ): `^(a`",
+		"Operator '$regex' of field 'request.stream[1].items[0].name' has an invalid regular expression: error parsing regexp: missing argument to repetition operator: `*`",
+	}, errorMessages)
+}
+
+func TestCompileRegex(t *testing.T) {
+	regex, err := compileRegex("^a+$")
+	assert.Nil(t, err)
+	cached, _ := compileRegex("^a+$")
+	assert.Same(t, regex, cached)
+
+	_, err = compileRegex("(")
+	assert.NotNil(t, err)
+	_, cachedErr := compileRegex("(")
+	assert.Equal(t, err, cachedErr)
+}
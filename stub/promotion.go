@@ -0,0 +1,95 @@
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PromotionOptions tell which recordings are turned into stubs and how their requests are matched
+type PromotionOptions struct {
+	Recordings   []int    `json:"recordings,omitempty"`   // positions of the recordings, in the order they are listed. All of them when empty.
+	PartialMatch bool     `json:"partialMatch,omitempty"` // matches the requests partially instead of exactly
+	DropMetadata []string `json:"dropMetadata,omitempty"` // metadata keys not to match on, like request ids or timestamps. Case-insensitive.
+	// KeepTransportMetadata keeps the metadata set by the gRPC transport, like user-agent or :authority, which is dropped otherwise
+	KeepTransportMetadata bool `json:"keepTransportMetadata,omitempty"`
+}
+
+// transportMetadata are the metadata keys gRPC sets on every call, which would tie the stubs to the client that was recorded
+var transportMetadata = []string{":authority", "content-type", "user-agent", "grpc-accept-encoding", "grpc-trace-bin", "grpc-tags-bin"}
+
+// PromoteRecordings turns the recordings selected into stubs, applying the options.
+// The store holds a single stub per request, so only the first recording of each request is kept.
+func PromoteRecordings(recordings []*Stub, options PromotionOptions) ([]*Stub, error) {
+	selected := recordings
+	if len(options.Recordings) > 0 {
+		selected = make([]*Stub, 0, len(options.Recordings))
+		for _, i := range options.Recordings {
+			if i < 0 || i >= len(recordings) {
+				return nil, fmt.Errorf("recording %d does not exist", i)
+			}
+			selected = append(selected, recordings[i])
+		}
+	}
+	promoted := make([]*Stub, 0, len(selected))
+	seen := make(map[string]bool, len(selected))
+	for _, recording := range selected {
+		s := promote(recording, options)
+		id := fmt.Sprintf("%s %s", s.FullMethod, s.Key())
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		promoted = append(promoted, s)
+	}
+	return promoted, nil
+}
+
+// promote copies the recording into a stub, leaving the recording as it is
+func promote(recording *Stub, options PromotionOptions) *Stub {
+	request := *recording.Request
+	if options.PartialMatch {
+		request.Match = "partial"
+	}
+	dropped := options.DropMetadata
+	if !options.KeepTransportMetadata {
+		dropped = append(append([]string{}, dropped...), transportMetadata...)
+	}
+	request.Metadata = dropMetadata(request.Metadata, dropped)
+	return &Stub{
+		FullMethod: recording.FullMethod,
+		Type:       recording.Type,
+		Request:    &request,
+		Response:   copyResponse(recording.Response),
+	}
+}
+
+// copyResponse returns a deep copy of the response so that changing the stub doesn't change the recording
+func copyResponse(response *StubResponse) *StubResponse {
+	if response == nil {
+		return nil
+	}
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		return response
+	}
+	copied := new(StubResponse)
+	if err := json.Unmarshal(bytes, copied); err != nil {
+		return response
+	}
+	return copied
+}
+
+// dropMetadata returns a copy of the metadata without the keys
+func dropMetadata(md map[string][]string, keys []string) map[string][]string {
+	kept := make(map[string][]string, len(md))
+	for key, values := range md {
+		if !containsValue(keys, func(dropped string) bool { return strings.EqualFold(dropped, key) }) {
+			kept[key] = append([]string{}, values...)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
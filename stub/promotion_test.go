@@ -0,0 +1,85 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func recording(request, response string, md map[string][]string) *Stub {
+	return &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "exact", Content: JsonString(request), Metadata: md},
+		Response:   &StubResponse{Type: "success", Content: JsonString(response)},
+	}
+}
+
+func TestPromoteRecordings_Dedupe(t *testing.T) {
+	recordings := []*Stub{
+		recording(`{"name":"a"}`, `{"greeting":"hi a"}`, nil),
+		recording(`{"name":"b"}`, `{"greeting":"hi b"}`, nil),
+		recording(`{"name":"a"}`, `{"greeting":"hi a"}`, nil),
+		recording(`{"name":"a"}`, `{"greeting":"hello a"}`, nil),
+	}
+	stubs, err := PromoteRecordings(recordings, PromotionOptions{})
+	assert.Nil(t, err)
+	// the first response recorded for a request is kept
+	assert.Equal(t, []*Stub{recordings[0], recordings[1]}, stubs)
+}
+
+func TestPromoteRecordings_Selected(t *testing.T) {
+	recordings := []*Stub{
+		recording(`{"name":"a"}`, `{"greeting":"hi a"}`, nil),
+		recording(`{"name":"b"}`, `{"greeting":"hi b"}`, nil),
+	}
+	stubs, err := PromoteRecordings(recordings, PromotionOptions{Recordings: []int{1}})
+	assert.Nil(t, err)
+	assert.Equal(t, []*Stub{recordings[1]}, stubs)
+
+	_, err = PromoteRecordings(recordings, PromotionOptions{Recordings: []int{2}})
+	assert.EqualError(t, err, "recording 2 does not exist")
+}
+
+func TestPromoteRecordings_Relaxed(t *testing.T) {
+	recordings := []*Stub{
+		recording(`{"name":"a"}`, `{"greeting":"hi a"}`, map[string][]string{"x-request-id": {"1"}, "tenant": {"t1"}}),
+		recording(`{"name":"a"}`, `{"greeting":"hi a"}`, map[string][]string{"x-request-id": {"2"}, "tenant": {"t1"}}),
+	}
+	stubs, err := PromoteRecordings(recordings, PromotionOptions{PartialMatch: true, DropMetadata: []string{"X-Request-Id"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stubs))
+	assert.Equal(t, &StubRequest{Match: "partial", Content: `{"name":"a"}`, Metadata: map[string][]string{"tenant": {"t1"}}}, stubs[0].Request)
+	// the recordings are left as they were
+	assert.Equal(t, "exact", recordings[0].Request.Match)
+	assert.Equal(t, []string{"1"}, recordings[0].Request.Metadata["x-request-id"])
+}
+
+func TestPromoteRecordings_TransportMetadata(t *testing.T) {
+	md := map[string][]string{":authority": {"localhost:50010"}, "content-type": {"application/grpc"}, "user-agent": {"grpc-go/1.35.0"}, "tenant": {"t1"}}
+	recordings := []*Stub{recording(`{"name":"a"}`, `{"greeting":"hi a"}`, md)}
+
+	stubs, err := PromoteRecordings(recordings, PromotionOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"tenant": {"t1"}}, stubs[0].Request.Metadata)
+
+	stubs, err = PromoteRecordings(recordings, PromotionOptions{KeepTransportMetadata: true})
+	assert.Nil(t, err)
+	assert.Equal(t, md, stubs[0].Request.Metadata)
+}
+
+func TestPromoteRecordings_CopiesResponse(t *testing.T) {
+	recordings := []*Stub{recording(`{"name":"a"}`, `{"greeting":"hi a"}`, map[string][]string{"tenant": {"t1"}})}
+	recordings[0].Response.Headers = map[string][]string{"x-upstream": {"1"}}
+
+	stubs, err := PromoteRecordings(recordings, PromotionOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, recordings[0].Response, stubs[0].Response)
+	assert.NotSame(t, recordings[0].Response, stubs[0].Response)
+
+	stubs[0].Response.Content = `{"greeting":"changed"}`
+	stubs[0].Response.Headers["x-upstream"][0] = "2"
+	stubs[0].Request.Metadata["tenant"][0] = "t2"
+	assert.Equal(t, JsonString(`{"greeting":"hi a"}`), recordings[0].Response.Content)
+	assert.Equal(t, []string{"1"}, recordings[0].Response.Headers["x-upstream"])
+	assert.Equal(t, []string{"t1"}, recordings[0].Request.Metadata["tenant"])
+}
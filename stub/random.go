@@ -0,0 +1,30 @@
+package stub
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// random is shared by everything in the stubs that is random so that a single seed makes them reproducible
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+var randomMutex sync.Mutex
+
+// SetRandomSeed seeds everything random in the stubs, like delays, faults and templates, to make test runs reproducible
+func SetRandomSeed(seed int64) {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	random = rand.New(rand.NewSource(seed))
+}
+
+func randomFloat64() float64 {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return random.Float64()
+}
+
+func randomNormFloat64() float64 {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return random.NormFloat64()
+}
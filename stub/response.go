@@ -1,16 +1,20 @@
 package stub
 
 import (
+	"context"
 	"fmt"
 	"github.com/golang/protobuf/jsonpb"
 	githubproto "github.com/golang/protobuf/proto"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	protojson22 "google.golang.org/protobuf/encoding/protojson"
 	proto22 "google.golang.org/protobuf/proto"
 	protoreflect22 "google.golang.org/protobuf/reflect/protoreflect"
 	"strings"
+	"time"
 )
 
 var errorEngine CustomErrorEngine
@@ -19,23 +23,170 @@ func SetErrorEngine(engine CustomErrorEngine) {
 	errorEngine = engine
 }
 
+// GetResponse creates the response of the stub matched. For sequences it is the next response of the sequence.
 func GetResponse(stub *Stub, requestJson string, resp interface{}) (interface{}, error) {
+	resp, _, err := GetDelayedResponse(context.Background(), stub, requestJson, resp)
+	return resp, err
+}
+
+// GetDelayedResponse creates the response of the stub matched like GetResponse and also returns how long to wait before replying.
+// The context is the one of the call, whose metadata can be used by response templates.
+func GetDelayedResponse(ctx context.Context, stub *Stub, requestJson string, resp interface{}) (interface{}, time.Duration, error) {
 	if stub == nil {
-		return nil, nil
+		return nil, 0, nil
 	}
-	if stub.Response.Type == "error" {
-		return createErrorResponse(errorEngine, stub.Response.Error)
+	response := nextResponse(stub)
+	delay := responseDelay(stub, response)
+	SetResponseMetadata(ctx, responseWithMetadata(stub, response))
+	if response.Type == "error" {
+		resp, err := createErrorResponse(errorEngine, response.Error)
+		return resp, delay, err
 	}
-	resp, transformErr := jsonToResponse(stub.Response.Content.String(), resp)
+	if response.Type == "stream" || response.Type == "conversation" {
+		return nil, 0, status.Errorf(codes.FailedPrecondition, "%s responses can only be used for streaming methods", response.Type)
+	}
+	resp, transformErr := renderResponse(ctx, response.Template || stub.Response.Template, response.Content, requestJson, resp)
 	if transformErr != nil {
 		log.WithFields(log.Fields{"Error": transformErr.Error()}).
 			Errorf("Error handling request %s --> %s", stub.FullMethod, requestJson)
 
-		return nil, fmt.Errorf("could not unmarshal response")
+		return nil, 0, fmt.Errorf("could not unmarshal response")
 	}
-	log.WithFields(log.Fields{"response": resp}).
+	log.WithFields(log.Fields{"response": resp, "delay": delay}).
 		Infof("Found MOCK response for %s --> %s", stub.FullMethod, requestJson)
-	return resp, nil
+	return resp, delay, nil
+}
+
+// StreamResponse is a message ready to be sent on a stream after waiting for Delay
+type StreamResponse struct {
+	Message interface{}
+	Delay   time.Duration
+}
+
+// GetStreamResponse returns the messages to send for a server streaming call, how long to wait before starting the stream
+// and the status to close the stream with.
+// A 'success' stub is sent as a single message and an 'error' stub closes the stream without sending any message.
+func GetStreamResponse(ctx context.Context, stub *Stub, requestJson string, newResponse func() interface{}) ([]StreamResponse, time.Duration, error) {
+	if stub == nil {
+		return nil, 0, nil
+	}
+	response := nextResponse(stub)
+	delay := responseDelay(stub, response)
+	SetResponseMetadata(ctx, responseWithMetadata(stub, response))
+	switch response.Type {
+	case "error":
+		_, err := createErrorResponse(errorEngine, response.Error)
+		return nil, delay, err
+	case "success":
+		resp, transformErr := renderResponse(ctx, response.Template || stub.Response.Template, response.Content, requestJson, newResponse())
+		if transformErr != nil {
+			log.WithFields(log.Fields{"Error": transformErr.Error()}).
+				Errorf("Error handling request %s --> %s", stub.FullMethod, requestJson)
+
+			return nil, 0, fmt.Errorf("could not unmarshal response")
+		}
+		return []StreamResponse{{Message: resp}}, delay, nil
+	}
+	messages, err := getStreamMessages(ctx, stub, stub.Response.Stream, requestJson, newResponse)
+	if err != nil {
+		return nil, 0, err
+	}
+	log.WithFields(log.Fields{"messages": len(messages), "delay": delay}).
+		Infof("Found MOCK stream response for %s --> %s", stub.FullMethod, requestJson)
+	if stub.Response.Error != nil {
+		_, err := createErrorResponse(errorEngine, stub.Response.Error)
+		return messages, delay, err
+	}
+	return messages, delay, nil
+}
+
+// responseDelay samples the delay of the response served, falling back to the delay of the stub's response for sequences
+func responseDelay(stub *Stub, response *StubResponse) time.Duration {
+	if response.Delay != nil {
+		return response.Delay.Duration()
+	}
+	return stub.Response.Delay.Duration()
+}
+
+// responseWithMetadata returns the response served with the headers and trailers to send with it.
+// Responses of a sequence without headers or trailers use the ones of the stub's response.
+func responseWithMetadata(stub *Stub, response *StubResponse) *StubResponse {
+	served := *response
+	if served.Headers == nil {
+		served.Headers = stub.Response.Headers
+	}
+	if served.Trailers == nil {
+		served.Trailers = stub.Response.Trailers
+	}
+	return &served
+}
+
+// SetResponseMetadata sets the headers and trailers of the response on the call, which gRPC sends along with the response.
+// Contexts that aren't the ones of a gRPC call, like the ones used to validate stubs, are ignored.
+func SetResponseMetadata(ctx context.Context, response *StubResponse) {
+	if len(response.Headers) > 0 {
+		if err := grpc.SetHeader(ctx, toMetadata(response.Headers)); err != nil {
+			log.Debugf("Could not set the response headers: %s", err)
+		}
+	}
+	if len(response.Trailers) > 0 {
+		if err := grpc.SetTrailer(ctx, toMetadata(response.Trailers)); err != nil {
+			log.Debugf("Could not set the response trailers: %s", err)
+		}
+	}
+}
+
+func toMetadata(values map[string][]string) metadata.MD {
+	md := metadata.MD{}
+	for key, keyValues := range values {
+		md.Append(key, keyValues...)
+	}
+	return md
+}
+
+// nextResponse returns the response to serve for the stub, moving its sequence forward if it has one
+func nextResponse(stub *Stub) *StubResponse {
+	if stub.Response.Type == "sequence" && stub.Response.Sequence != nil && len(stub.Response.Sequence.Responses) > 0 {
+		return stub.Response.Sequence.next()
+	}
+	return stub.Response
+}
+
+// GetConversationResponse returns the messages to send when a conversation reaches the step.
+// For 'close' steps no message is returned and the error is the status the stream must be closed with.
+func GetConversationResponse(ctx context.Context, stub *Stub, step ConversationStep, requestJson string, newResponse func() interface{}) ([]StreamResponse, error) {
+	if step.Type == "close" {
+		if step.Error == nil {
+			return nil, nil
+		}
+		_, err := createErrorResponse(errorEngine, step.Error)
+		return nil, err
+	}
+	messages, err := getStreamMessages(ctx, stub, step.Send, requestJson, newResponse)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"messages": len(messages)}).
+		Infof("Found MOCK conversation response for %s --> %s", stub.FullMethod, requestJson)
+	return messages, nil
+}
+
+func getStreamMessages(ctx context.Context, stub *Stub, streamMessages []StreamMessage, requestJson string, newResponse func() interface{}) ([]StreamResponse, error) {
+	messages := make([]StreamResponse, 0, len(streamMessages))
+	for _, streamMessage := range streamMessages {
+		resp, transformErr := renderResponse(ctx, stub.Response.Template, streamMessage.Content, requestJson, newResponse())
+		if transformErr != nil {
+			log.WithFields(log.Fields{"Error": transformErr.Error()}).
+				Errorf("Error handling request %s --> %s", stub.FullMethod, requestJson)
+
+			return nil, fmt.Errorf("could not unmarshal response")
+		}
+		messages = append(messages, StreamResponse{
+			Message: resp,
+			Delay:   time.Duration(streamMessage.Delay) * time.Millisecond,
+		})
+	}
+	return messages, nil
 }
 
 func createErrorResponse(errorEngine CustomErrorEngine, stubError *ErrorResponse) (interface{}, error) {
@@ -75,6 +226,15 @@ func createErrorResponse(errorEngine CustomErrorEngine, stubError *ErrorResponse
 	return nil, st.Err()
 }
 
+// renderResponse turns the content into the response message, rendering it first if the response is a template
+func renderResponse(ctx context.Context, isTemplate bool, content JsonString, requestJson string, returnTypeInstance interface{}) (interface{}, error) {
+	jsonString, err := renderTemplate(ctx, isTemplate, content, requestJson)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToResponse(jsonString, returnTypeInstance)
+}
+
 func jsonToResponse(jsonString string, returnTypeInstance interface{}) (interface{}, error) {
 	var err error
 	if isCompatibleWithProtobug22(returnTypeInstance) {
@@ -0,0 +1,143 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/apipb"
+	"testing"
+	"time"
+)
+
+func newMethod() interface{} {
+	return new(apipb.Method)
+}
+
+func TestGetStreamResponse_StreamMessages(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type: "stream",
+			Stream: []StreamMessage{
+				{Content: "{\"name\":\"first\"}"},
+				{Content: "{\"name\":\"second\"}", Delay: 20},
+			},
+		},
+	}
+	messages, _, err := GetStreamResponse(context.Background(), s, "{}", newMethod)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "first", messages[0].Message.(*apipb.Method).Name)
+	assert.Equal(t, time.Duration(0), messages[0].Delay)
+	assert.Equal(t, "second", messages[1].Message.(*apipb.Method).Name)
+	assert.Equal(t, 20*time.Millisecond, messages[1].Delay)
+}
+
+func TestGetStreamResponse_StreamMessagesWithTerminalStatus(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type:   "stream",
+			Stream: []StreamMessage{{Content: "{\"name\":\"first\"}"}},
+			Error:  &ErrorResponse{Code: uint32(codes.Aborted), Message: "stream aborted"},
+		},
+	}
+	messages, _, err := GetStreamResponse(context.Background(), s, "{}", newMethod)
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, "stream aborted", status.Convert(err).Message())
+}
+
+func TestGetStreamResponse_SuccessIsSentAsSingleMessage(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type:    "success",
+			Content: "{\"name\":\"only\"}",
+		},
+	}
+	messages, _, err := GetStreamResponse(context.Background(), s, "{}", newMethod)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "only", messages[0].Message.(*apipb.Method).Name)
+}
+
+func TestGetStreamResponse_InvalidMessage(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type:   "stream",
+			Stream: []StreamMessage{{Content: "{\"unknown\":\"field\"}"}},
+		},
+	}
+	messages, _, err := GetStreamResponse(context.Background(), s, "{}", newMethod)
+	assert.Nil(t, messages)
+	assert.EqualError(t, err, "could not unmarshal response")
+}
+
+func newSequenceStub(exhausted string) *Stub {
+	return &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type: "sequence",
+			Sequence: &ResponseSequence{
+				Responses: []*StubResponse{
+					{Type: "error", Error: &ErrorResponse{Code: uint32(codes.Unavailable), Message: "unavailable"}},
+					{Type: "success", Content: "{\"name\":\"ok\"}"},
+				},
+				Exhausted: exhausted,
+				Error:     &ErrorResponse{Code: uint32(codes.ResourceExhausted), Message: "no more responses"},
+			},
+		},
+	}
+}
+
+func TestGetResponse_Sequence_RepeatsLastResponse(t *testing.T) {
+	s := newSequenceStub("repeat")
+
+	_, err := GetResponse(s, "{}", new(apipb.Method))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	for i := 0; i < 2; i++ {
+		resp, err := GetResponse(s, "{}", new(apipb.Method))
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", resp.(*apipb.Method).Name)
+	}
+}
+
+func TestGetResponse_Sequence_Cycles(t *testing.T) {
+	s := newSequenceStub("cycle")
+
+	codesServed := make([]codes.Code, 0)
+	for i := 0; i < 4; i++ {
+		_, err := GetResponse(s, "{}", new(apipb.Method))
+		codesServed = append(codesServed, status.Code(err))
+	}
+	assert.Equal(t, []codes.Code{codes.Unavailable, codes.OK, codes.Unavailable, codes.OK}, codesServed)
+}
+
+func TestGetResponse_Sequence_FailsWhenExhausted(t *testing.T) {
+	s := newSequenceStub("error")
+
+	GetResponse(s, "{}", new(apipb.Method))
+	GetResponse(s, "{}", new(apipb.Method))
+	_, err := GetResponse(s, "{}", new(apipb.Method))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "no more responses", status.Convert(err).Message())
+}
+
+func TestResponseWithMetadata_SequenceFallsBackToStubResponse(t *testing.T) {
+	s := newSequenceStub("repeat")
+	s.Response.Headers = map[string][]string{"x-page": {"stub"}}
+	s.Response.Trailers = map[string][]string{"x-rate-limit": {"10"}}
+	s.Response.Sequence.Responses[1].Headers = map[string][]string{"x-page": {"2"}}
+
+	first := responseWithMetadata(s, s.Response.Sequence.Responses[0])
+	assert.Equal(t, map[string][]string{"x-page": {"stub"}}, first.Headers)
+	assert.Equal(t, map[string][]string{"x-rate-limit": {"10"}}, first.Trailers)
+
+	second := responseWithMetadata(s, s.Response.Sequence.Responses[1])
+	assert.Equal(t, map[string][]string{"x-page": {"2"}}, second.Headers)
+	assert.Equal(t, map[string][]string{"x-rate-limit": {"10"}}, second.Trailers)
+	assert.Nil(t, s.Response.Sequence.Responses[1].Trailers)
+}
@@ -0,0 +1,99 @@
+package stub
+
+import (
+	"sort"
+	"sync"
+)
+
+// ScenarioStarted is the state every scenario is in before any of its stubs moves it to another state
+const ScenarioStarted = "Started"
+
+type Scenario struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+func NewInMemoryScenariosStore() ScenariosStore {
+	return &inMemoryScenariosStore{
+		States: make(map[string]string, 0),
+	}
+}
+
+type ScenariosStore interface {
+	GetState(name string) string
+	SetState(name, state string)
+	// CompareAndSetState moves the scenario to the state only if it is in the expected state, telling whether it was
+	CompareAndSetState(name, expected, state string) bool
+	GetAll() []Scenario
+	Reset(name string)
+	ResetAll()
+}
+
+type inMemoryScenariosStore struct {
+	// Current state of the scenarios that left the ScenarioStarted state
+	States map[string]string
+	mutex  sync.RWMutex
+}
+
+func (s *inMemoryScenariosStore) GetState(name string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.States[name]
+	if !ok {
+		return ScenarioStarted
+	}
+	return state
+}
+
+func (s *inMemoryScenariosStore) SetState(name, state string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.States[name] = state
+}
+
+func (s *inMemoryScenariosStore) CompareAndSetState(name, expected, state string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.States[name]
+	if !ok {
+		current = ScenarioStarted
+	}
+	if current != expected {
+		return false
+	}
+	if state != current {
+		s.States[name] = state
+	}
+	return true
+}
+
+func (s *inMemoryScenariosStore) GetAll() []Scenario {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	scenarios := make([]Scenario, 0)
+	for name, state := range s.States {
+		scenarios = append(scenarios, Scenario{Name: name, State: state})
+	}
+	sort.Slice(scenarios, func(i, j int) bool {
+		return scenarios[i].Name < scenarios[j].Name
+	})
+	return scenarios
+}
+
+func (s *inMemoryScenariosStore) Reset(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.States, name)
+}
+
+func (s *inMemoryScenariosStore) ResetAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.States = make(map[string]string, 0)
+}
@@ -0,0 +1,21 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInMemoryScenariosStore_CompareAndSetState(t *testing.T) {
+	scenarios := NewInMemoryScenariosStore()
+
+	assert.False(t, scenarios.CompareAndSetState("order", "PAID", "SHIPPED"))
+	assert.Equal(t, ScenarioStarted, scenarios.GetState("order"))
+
+	assert.True(t, scenarios.CompareAndSetState("order", ScenarioStarted, ScenarioStarted))
+	assert.Equal(t, []Scenario{}, scenarios.GetAll())
+
+	assert.True(t, scenarios.CompareAndSetState("order", ScenarioStarted, "PAID"))
+	assert.Equal(t, "PAID", scenarios.GetState("order"))
+	assert.False(t, scenarios.CompareAndSetState("order", ScenarioStarted, "CANCELLED"))
+	assert.Equal(t, "PAID", scenarios.GetState("order"))
+}
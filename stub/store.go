@@ -2,6 +2,7 @@ package stub
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -34,12 +35,13 @@ type StubsStore interface {
 type RecordingsStore interface {
 	Add(e *Stub) error
 	GetAllStubs() []*Stub
+	DeleteAll()
 }
 
 type inMemoryStubsStore struct {
 	// Stores the stubs registered.
 	// First map's key is a full method name
-	// Second map's key is a gRPC request payload in JSON format
+	// Second map's key is a gRPC request payload in JSON format (followed by the required state for stubs in a scenario)
 	// The data stub here would look like:
 	// /carvalhorr.proto.test.TestProtobuf/GetProtoTest ->
 	//               {\"customerId\":1593510,\"siteId\":10153291} -> stub1
@@ -49,7 +51,9 @@ type inMemoryStubsStore struct {
 	//               request 2 -> stub4
 	Stubs         map[string]map[string][]*Stub
 	AllowRepeated bool
-	mutex         sync.RWMutex
+	// number of stubs added so far, used to remember the order the stubs were added in
+	added uint64
+	mutex sync.RWMutex
 }
 
 func (s *inMemoryStubsStore) Add(e *Stub) error {
@@ -65,7 +69,9 @@ func (s *inMemoryStubsStore) Add(e *Stub) error {
 		return fmt.Errorf("stub already exist: %s -> %s", e.FullMethod, e.Request.String())
 	}
 
-	s.Stubs[e.FullMethod][e.Request.String()] = append(s.Stubs[e.FullMethod][e.Request.String()], e)
+	s.added++
+	e.added = s.added
+	s.Stubs[e.FullMethod][e.Key()] = append(s.Stubs[e.FullMethod][e.Key()], e)
 
 	return nil
 }
@@ -92,6 +98,7 @@ func (s *inMemoryStubsStore) GetStubsForMethod(method string) []*Stub {
 	return s.getStubsForMethod(method)
 }
 
+// getStubsForMethod returns the stubs for the method in the order they were added
 func (s *inMemoryStubsStore) getStubsForMethod(method string) []*Stub {
 	resp := make([]*Stub, 0)
 	for _, stubs := range s.Stubs[method] {
@@ -99,6 +106,7 @@ func (s *inMemoryStubsStore) getStubsForMethod(method string) []*Stub {
 			resp = append(resp, e)
 		}
 	}
+	sortByInsertionOrder(resp)
 	return resp
 }
 
@@ -110,6 +118,7 @@ func (s *inMemoryStubsStore) GetAllStubs() []*Stub {
 	for methodName := range s.Stubs {
 		allStubs = append(allStubs, s.getStubsForMethod(methodName)...)
 	}
+	sortByInsertionOrder(allStubs)
 
 	return allStubs
 }
@@ -122,7 +131,8 @@ func (s *inMemoryStubsStore) Update(e *Stub) error {
 		return fmt.Errorf("stub does not exist: %s -> %s", e.FullMethod, e.Request.String())
 	}
 
-	s.Stubs[e.FullMethod][e.Request.String()][0] = e
+	e.added = s.Stubs[e.FullMethod][e.Key()][0].added
+	s.Stubs[e.FullMethod][e.Key()][0] = e
 
 	return nil
 }
@@ -135,7 +145,7 @@ func (s *inMemoryStubsStore) Delete(e *Stub) error {
 		return fmt.Errorf("stub does not exist: %s -> %s", e.FullMethod, e.Request.String())
 	}
 
-	delete(s.Stubs[e.FullMethod], e.Request.String())
+	delete(s.Stubs[e.FullMethod], e.Key())
 
 	return nil
 }
@@ -149,7 +159,7 @@ func (s *inMemoryStubsStore) Exists(e *Stub) bool {
 
 func (s *inMemoryStubsStore) exists(e *Stub) bool {
 	stubsPerMethod := s.Stubs[e.FullMethod]
-	foundStub := stubsPerMethod[e.Request.String()]
+	foundStub := stubsPerMethod[e.Key()]
 	return foundStub != nil
 }
 
@@ -172,3 +182,9 @@ func (s *inMemoryStubsStore) DeleteAll() {
 		s.deleteAllForMethod(method)
 	}
 }
+
+func sortByInsertionOrder(stubs []*Stub) {
+	sort.Slice(stubs, func(i, j int) bool {
+		return stubs[i].added < stubs[j].added
+	})
+}
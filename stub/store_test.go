@@ -0,0 +1,34 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInMemoryStubsStore_GetAllStubs_InInsertionOrder(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	stubs := []*Stub{
+		{FullMethod: "method2", Request: &StubRequest{Match: "exact", Content: "{\"id\":1}"}},
+		{FullMethod: "method1", Request: &StubRequest{Match: "exact", Content: "{\"id\":2}"}},
+		{FullMethod: "method2", Request: &StubRequest{Match: "exact", Content: "{\"id\":3}"}},
+		{FullMethod: "method1", Request: &StubRequest{Match: "exact", Content: "{\"id\":4}"}},
+	}
+	for _, s := range stubs {
+		assert.Nil(t, store.Add(s))
+	}
+
+	assert.Equal(t, stubs, store.GetAllStubs())
+	assert.Equal(t, []*Stub{stubs[0], stubs[2]}, store.GetStubsForMethod("method2"))
+}
+
+func TestInMemoryStubsStore_Update_KeepsInsertionOrder(t *testing.T) {
+	store := NewInMemoryStubsStore()
+	first := &Stub{FullMethod: "method1", Request: &StubRequest{Match: "exact", Content: "{\"id\":1}"}}
+	second := &Stub{FullMethod: "method1", Request: &StubRequest{Match: "exact", Content: "{\"id\":2}"}}
+	store.Add(first)
+	store.Add(second)
+	updated := &Stub{FullMethod: "method1", Request: &StubRequest{Match: "exact", Content: "{\"id\":1}"}, Priority: 1}
+	assert.Nil(t, store.Update(updated))
+
+	assert.Equal(t, []*Stub{updated, second}, store.GetAllStubs())
+}
@@ -0,0 +1,125 @@
+package stub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Responses with template = true have their content rendered as a Go text/template before it is turned into the response
+// message, so that the content can depend on the call. The functions available are:
+// - request: the request received, read as JSON. For example {{request.name}} or {{request.user.id}}.
+//   For client streaming calls it is the array of messages received, for example {{(index request 0).name}}
+// - metadata "key": the first value of the metadata key, empty if it wasn't sent
+// - now: the current time in RFC 3339 format. A Go layout can be given, for example {{now "2006-01-02"}}
+// - uuid: a random UUID (version 4)
+// - randomInt min max: a random integer between min and max, both inclusive
+// - counter "name": the next value of the counter, starting at 1. Counters are shared by all the stubs.
+// - json value: the value encoded as JSON, for example to insert a string with its quotes escaped
+//
+// Templates usually aren't valid JSON until they are rendered, for example {"id": {{request.id}}},
+// so their content is only validated once rendered. In stubs files they are written as JSON strings,
+// for example "content": "{\"id\": {{request.id}}}".
+
+var counters = make(map[string]uint64)
+var countersMutex sync.Mutex
+
+// renderTemplate renders the content when it is a template or returns it as it is otherwise
+func renderTemplate(ctx context.Context, isTemplate bool, content JsonString, requestJson string) (string, error) {
+	if !isTemplate {
+		return content.String(), nil
+	}
+	var request interface{}
+	json.Unmarshal([]byte(requestJson), &request)
+	md, _ := metadata.FromIncomingContext(ctx)
+	tmpl, err := parseTemplate(content.templateText(), template.FuncMap{
+		"request": func() interface{} {
+			return request
+		},
+		"metadata": func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	buffer := new(bytes.Buffer)
+	if err := tmpl.Execute(buffer, nil); err != nil {
+		return "", fmt.Errorf("could not render response template: %w", err)
+	}
+	return buffer.String(), nil
+}
+
+// parseTemplate parses the content with the functions that don't depend on the call and the ones provided
+func parseTemplate(content string, callFunctions template.FuncMap) (*template.Template, error) {
+	functions := template.FuncMap{
+		"request":   func() interface{} { return nil },
+		"metadata":  func(key string) string { return "" },
+		"now":       now,
+		"uuid":      newUUID,
+		"randomInt": randomInt,
+		"counter":   nextCounter,
+		"json":      toJsonValue,
+	}
+	for name, function := range callFunctions {
+		functions[name] = function
+	}
+	tmpl, err := template.New("response").Funcs(functions).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func now(layout ...string) string {
+	if len(layout) > 0 {
+		return time.Now().Format(layout[0])
+	}
+	return time.Now().Format(time.RFC3339)
+}
+
+func newUUID() string {
+	uuid := make([]byte, 16)
+	randomMutex.Lock()
+	random.Read(uuid)
+	randomMutex.Unlock()
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+func randomInt(min, max int) int {
+	if max < min {
+		min, max = max, min
+	}
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return min + random.Intn(max-min+1)
+}
+
+func nextCounter(name string) uint64 {
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+	counters[name]++
+	return counters[name]
+}
+
+// isResponseValid checks the content is a valid response message or, when it is a template, that the template can be parsed
+func (j JsonString) isResponseValid(t protoreflect.MessageDescriptor, baseName string, isTemplate bool) (isValid bool, errorMessages []string) {
+	if !isTemplate {
+		return j.isJsonValid(t, baseName)
+	}
+	if _, err := parseTemplate(j.templateText(), nil); err != nil {
+		return false, []string{fmt.Sprintf("%s: %s", baseName, err)}
+	}
+	return true, nil
+}
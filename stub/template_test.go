@@ -0,0 +1,88 @@
+package stub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/apipb"
+	"regexp"
+	"testing"
+)
+
+func TestGetDelayedResponse_Template(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response: &StubResponse{
+			Type:     "success",
+			Template: true,
+			Content:  `{"name":"Hello {{request.user.name}}","requestTypeUrl":{{json (metadata "x-request-id")}}}`,
+		},
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", `id-"1"`))
+
+	resp, _, err := GetDelayedResponse(ctx, s, `{"user":{"name":"John"}}`, new(apipb.Method))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello John", resp.(*apipb.Method).Name)
+	assert.Equal(t, `id-"1"`, resp.(*apipb.Method).RequestTypeUrl)
+}
+
+func TestGetDelayedResponse_NotATemplate(t *testing.T) {
+	s := &Stub{
+		FullMethod: "method1",
+		Response:   &StubResponse{Type: "success", Content: `{"name":"{{request.name}}"}`},
+	}
+	resp, _, err := GetDelayedResponse(context.Background(), s, `{"name":"John"}`, new(apipb.Method))
+	assert.Nil(t, err)
+	assert.Equal(t, "{{request.name}}", resp.(*apipb.Method).Name)
+}
+
+func TestRenderTemplate_Functions(t *testing.T) {
+	rendered, err := renderTemplate(context.Background(), true, `{{uuid}} {{randomInt 5 5}} {{now "2006"}} {{counter "test-counter"}} {{counter "test-counter"}}`, `{}`)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} 5 \d{4} 1 2$`), rendered)
+}
+
+func TestRenderTemplate_StreamRequest(t *testing.T) {
+	rendered, err := renderTemplate(context.Background(), true, `{{(index request 1).name}}`, `[{"name":"a"},{"name":"b"}]`)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", rendered)
+}
+
+func TestIsStubValid_Template(t *testing.T) {
+	descriptor := (&apipb.Method{}).ProtoReflect().Descriptor()
+	s := &Stub{
+		FullMethod: "method1",
+		Type:       "mock",
+		Request:    &StubRequest{Match: "partial", Content: `{}`},
+		Response:   &StubResponse{Type: "success", Template: true, Content: `{"syntax": {{request.syntax}}}`},
+	}
+	isValid, errMsgs := IsStubValid(s, descriptor, descriptor)
+	assert.True(t, isValid, errMsgs)
+
+	s.Response.Content = `{"name": "{{request.name"}`
+	isValid, errMsgs = IsStubValid(s, descriptor, descriptor)
+	assert.False(t, isValid)
+	assert.Len(t, errMsgs, 1)
+	assert.Contains(t, errMsgs[0], "response.content: invalid response template:")
+}
+
+func TestJsonString_TemplateRoundTrip(t *testing.T) {
+	var content JsonString
+	assert.Nil(t, content.UnmarshalJSON([]byte(`"{\"id\": {{request.id}}}"`)))
+	assert.Equal(t, `{"id": {{request.id}}}`, content.templateText())
+	data, err := content.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `"{\"id\": {{request.id}}}"`, string(data))
+
+	// templates set as their text are written as JSON strings
+	content = `{"id": {{request.id}}}`
+	data, err = content.MarshalJSON()
+	assert.Nil(t, err)
+	var loaded JsonString
+	assert.Nil(t, loaded.UnmarshalJSON(data))
+	assert.Equal(t, `{"id": {{request.id}}}`, loaded.templateText())
+
+	rendered, err := renderTemplate(context.Background(), true, loaded, `{"id":7}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id": 7}`, rendered)
+}
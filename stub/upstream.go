@@ -0,0 +1,119 @@
+package stub
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Upstream is the server the calls no stub matches are forwarded to, and recorded if enabled.
+// It can be set for all the calls, for the calls to a service or for the calls to a method. The most specific one is used.
+// Like with 'forward' stubs, only unary calls are forwarded: the streaming calls no stub matches end with the status set
+// for the calls not matched, NOT_FOUND by default.
+type Upstream struct {
+	Target  string       `json:"target"` // empty for all the calls, a service like 'package.Service' or a full method like '/package.Service/Method'
+	Forward *StubForward `json:"forward"`
+}
+
+// IsValid checks the upstream can receive the calls of its target
+func (u *Upstream) IsValid() (isValid bool, errMsgs []string) {
+	if strings.HasPrefix(u.Target, "/") {
+		if parts := strings.Split(u.Target, "/"); len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			errMsgs = append(errMsgs, fmt.Sprintf("Upstream target '%s' is not a valid full method name.", u.Target))
+		}
+	} else if strings.Contains(u.Target, "/") {
+		errMsgs = append(errMsgs, fmt.Sprintf("Upstream target '%s' is not a valid service name.", u.Target))
+	}
+	if u.Forward == nil || u.Forward.ServerAddress == "" {
+		errMsgs = append(errMsgs, "Upstream server address can't be empty.")
+	}
+	if u.Forward != nil {
+		errMsgs = append(errMsgs, u.Forward.isValid("Upstream")...)
+	}
+	return len(errMsgs) == 0, errMsgs
+}
+
+// upstreamTargets returns the targets the calls to the method can be forwarded to, from the most to the least specific
+func upstreamTargets(fullMethod string) []string {
+	targets := []string{fullMethod}
+	if parts := strings.Split(fullMethod, "/"); len(parts) == 3 {
+		targets = append(targets, parts[1])
+	}
+	return append(targets, "")
+}
+
+func NewInMemoryUpstreamsStore() UpstreamsStore {
+	return &inMemoryUpstreamsStore{
+		Upstreams: make(map[string]*StubForward, 0),
+	}
+}
+
+type UpstreamsStore interface {
+	Get(target string) *StubForward
+	Set(target string, forward *StubForward)
+	GetAll() []Upstream
+	Delete(target string)
+	DeleteAll()
+	// Resolve returns the upstream the calls to the method are forwarded to when no stub matches them, if any
+	Resolve(fullMethod string) *StubForward
+}
+
+type inMemoryUpstreamsStore struct {
+	Upstreams map[string]*StubForward
+	mutex     sync.RWMutex
+}
+
+func (s *inMemoryUpstreamsStore) Get(target string) *StubForward {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.Upstreams[target]
+}
+
+func (s *inMemoryUpstreamsStore) Set(target string, forward *StubForward) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Upstreams[target] = forward
+}
+
+func (s *inMemoryUpstreamsStore) GetAll() []Upstream {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	upstreams := make([]Upstream, 0, len(s.Upstreams))
+	for target, forward := range s.Upstreams {
+		upstreams = append(upstreams, Upstream{Target: target, Forward: forward})
+	}
+	sort.Slice(upstreams, func(i, j int) bool {
+		return upstreams[i].Target < upstreams[j].Target
+	})
+	return upstreams
+}
+
+func (s *inMemoryUpstreamsStore) Delete(target string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.Upstreams, target)
+}
+
+func (s *inMemoryUpstreamsStore) DeleteAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Upstreams = make(map[string]*StubForward, 0)
+}
+
+func (s *inMemoryUpstreamsStore) Resolve(fullMethod string) *StubForward {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, target := range upstreamTargets(fullMethod) {
+		if forward, ok := s.Upstreams[target]; ok {
+			return forward
+		}
+	}
+	return nil
+}
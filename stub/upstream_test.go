@@ -0,0 +1,52 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInMemoryUpstreamsStore_Resolve(t *testing.T) {
+	store := NewInMemoryUpstreamsStore()
+	assert.Nil(t, store.Resolve("/pkg.Service/Method"))
+
+	server := &StubForward{ServerAddress: "server:1"}
+	service := &StubForward{ServerAddress: "service:1"}
+	method := &StubForward{ServerAddress: "method:1", Record: true}
+	store.Set("", server)
+	store.Set("pkg.Service", service)
+	store.Set("/pkg.Service/Method", method)
+
+	assert.Equal(t, method, store.Resolve("/pkg.Service/Method"))
+	assert.Equal(t, service, store.Resolve("/pkg.Service/Other"))
+	assert.Equal(t, server, store.Resolve("/pkg.Other/Method"))
+	assert.Equal(t, []Upstream{
+		{Target: "", Forward: server},
+		{Target: "/pkg.Service/Method", Forward: method},
+		{Target: "pkg.Service", Forward: service},
+	}, store.GetAll())
+
+	store.Delete("/pkg.Service/Method")
+	assert.Equal(t, service, store.Resolve("/pkg.Service/Method"))
+	store.DeleteAll()
+	assert.Nil(t, store.Resolve("/pkg.Service/Method"))
+}
+
+func TestUpstream_IsValid(t *testing.T) {
+	forward := &StubForward{ServerAddress: "localhost:1"}
+	for _, target := range []string{"", "pkg.Service", "/pkg.Service/Method"} {
+		isValid, errMsgs := (&Upstream{Target: target, Forward: forward}).IsValid()
+		assert.True(t, isValid, target)
+		assert.Empty(t, errMsgs)
+	}
+
+	isValid, errMsgs := (&Upstream{Target: "/pkg.Service"}).IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{
+		"Upstream target '/pkg.Service' is not a valid full method name.",
+		"Upstream server address can't be empty.",
+	}, errMsgs)
+
+	isValid, errMsgs = (&Upstream{Target: "pkg.Service/Method", Forward: forward}).IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{"Upstream target 'pkg.Service/Method' is not a valid service name."}, errMsgs)
+}
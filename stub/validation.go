@@ -35,11 +35,55 @@ func IsStubValid(stub *Stub, request, response protoreflect.MessageDescriptor) (
 	if !valid {
 		return valid, errorMessages
 	}
-	reqValid, reqErrorMessages := stub.Request.Content.isJsonValid(request, "request.content")
+	reqValid, reqErrorMessages := true, make([]string, 0)
+	if (!stub.Request.IsStream() && !stub.isConversation() && stub.Request.usesContent()) || stub.Request.Content != "" {
+		reqValid, reqErrorMessages = stub.Request.Content.isJsonValid(request, "request.content")
+	}
+	if stub.Request.Match == "cel" && !stub.isConversation() {
+		expressionErrorMessages := stub.Request.isValidExpression(request)
+		reqValid = reqValid && len(expressionErrorMessages) == 0
+		reqErrorMessages = append(reqErrorMessages, expressionErrorMessages...)
+	}
+	for i, content := range stub.Request.Stream {
+		messageValid, messageErrorMessages := content.isJsonValid(request, fmt.Sprintf("request.stream[%d]", i))
+		reqValid = reqValid && messageValid
+		reqErrorMessages = append(reqErrorMessages, messageErrorMessages...)
+	}
 	respValid := true
 	respErrorMessages := make([]string, 0)
 	if stub.Type == "mock" && stub.Response.Type == "success" {
-		respValid, respErrorMessages = stub.Response.Content.isJsonValid(response, "response.content")
+		respValid, respErrorMessages = stub.Response.Content.isResponseValid(response, "response.content", stub.Response.Template)
+	}
+	if stub.Type == "mock" && stub.Response.Type == "sequence" && stub.Response.Sequence != nil {
+		for i, sequenceResponse := range stub.Response.Sequence.Responses {
+			if sequenceResponse == nil || sequenceResponse.Type != "success" {
+				continue
+			}
+			messageValid, messageErrorMessages := sequenceResponse.Content.isResponseValid(response, fmt.Sprintf("response.sequence.responses[%d].content", i), sequenceResponse.Template || stub.Response.Template)
+			respValid = respValid && messageValid
+			respErrorMessages = append(respErrorMessages, messageErrorMessages...)
+		}
+	}
+	if stub.Type == "mock" && stub.Response.Type == "conversation" {
+		for i, step := range stub.Response.Conversation {
+			if step.Type == "expect" {
+				messageValid, messageErrorMessages := step.Content.isJsonValid(request, fmt.Sprintf("response.conversation[%d].content", i))
+				reqValid = reqValid && messageValid
+				reqErrorMessages = append(reqErrorMessages, messageErrorMessages...)
+			}
+			for j, streamMessage := range step.Send {
+				messageValid, messageErrorMessages := streamMessage.Content.isResponseValid(response, fmt.Sprintf("response.conversation[%d].send[%d].content", i, j), stub.Response.Template)
+				respValid = respValid && messageValid
+				respErrorMessages = append(respErrorMessages, messageErrorMessages...)
+			}
+		}
+	}
+	if stub.Type == "mock" && stub.Response.Type == "stream" {
+		for i, streamMessage := range stub.Response.Stream {
+			messageValid, messageErrorMessages := streamMessage.Content.isResponseValid(response, fmt.Sprintf("response.stream[%d].content", i), stub.Response.Template)
+			respValid = respValid && messageValid
+			respErrorMessages = append(respErrorMessages, messageErrorMessages...)
+		}
 	}
 	errorMessages = append(errorMessages, reqErrorMessages...)
 	errorMessages = append(errorMessages, respErrorMessages...)
@@ -73,6 +117,10 @@ func isJsonValid(t protoreflect.MessageDescriptor, json map[string]interface{},
 			errorMessages = append(errorMessages, fmt.Sprintf("Field '%s.%s' does not exist", baseName, jsonName))
 			continue
 		}
+		if operators, ok := asOperators(fieldValue); ok {
+			errorMessages = append(errorMessages, validateOperators(field, operators, baseName+"."+jsonName)...)
+			continue
+		}
 		if fieldValue == nil {
 			continue
 		}
@@ -128,18 +176,63 @@ func (stub *Stub) IsValid() (isValid bool, errMsgs []string) {
 	isValid = isValid && forwardValid
 	errMsgs = append(errMsgs, forwardErrMsgs...)
 
+	errMsgs = append(errMsgs, stub.Fault.isValid("Fault")...)
+	errMsgs = append(errMsgs, stub.validateRegexps()...)
+
+	// Validate scenario
+	if stub.Scenario == "" && (stub.RequiredState != "" || stub.NewState != "") {
+		errMsgs = append(errMsgs, "Scenario can't be empty when a required state or new state is provided.")
+	}
+
 	return len(errMsgs) == 0, errMsgs
 }
 
+// validateRegexps checks the $regex operators of the contents the calls are matched against. They are checked again
+// with the rest of the operators when the contents are validated against the request message.
+func (stub *Stub) validateRegexps() (errMsgs []string) {
+	if stub.Request != nil {
+		errMsgs = append(errMsgs, stub.Request.Content.validateRegexps("request.content")...)
+		for i, content := range stub.Request.Stream {
+			errMsgs = append(errMsgs, content.validateRegexps(fmt.Sprintf("request.stream[%d]", i))...)
+		}
+	}
+	if stub.Response != nil {
+		for i, step := range stub.Response.Conversation {
+			errMsgs = append(errMsgs, step.Content.validateRegexps(fmt.Sprintf("response.conversation[%d].content", i))...)
+		}
+	}
+	return errMsgs
+}
+
 func (stub *Stub) isValidRequest() (isValid bool, errMsgs []string) {
 	if stub.Request == nil {
 		errMsgs = append(errMsgs, "Request can't be empty.")
+	} else {
+		errMsgs = append(errMsgs, stub.Request.isValidMetadataMatch()...)
+	}
+	if stub.isConversation() {
+		return len(errMsgs) == 0, errMsgs // conversations are matched by metadata only
 	}
-	if stub.Request.Content == "" {
+	if stub.Request.Content == "" && !stub.Request.IsStream() && stub.Request.usesContent() {
 		errMsgs = append(errMsgs, "Request content can't be empty.")
 	}
-	if stub.Request.Match != "exact" && stub.Request.Match != "partial" {
-		errMsgs = append(errMsgs, "Request matching type can only be either 'exact' or 'partial'.")
+	if stub.Request.Match != "exact" && stub.Request.Match != "partial" && stub.Request.Match != "jsonpath" && stub.Request.Match != "cel" {
+		errMsgs = append(errMsgs, "Request matching type can only be either 'exact', 'partial', 'jsonpath' or 'cel'.")
+	}
+	if !stub.Request.usesContent() && stub.Request.IsStream() {
+		errMsgs = append(errMsgs, fmt.Sprintf("Request matching type '%s' can't be used with a stream.", stub.Request.Match))
+	}
+	if stub.Request.Match == "jsonpath" {
+		errMsgs = append(errMsgs, stub.Request.isValidPaths()...)
+	}
+	if stub.Request.Match == "cel" && stub.Request.Expression == "" {
+		errMsgs = append(errMsgs, "Request expression can't be empty when the matching type is 'cel'.")
+	}
+	if stub.Request.IsStream() && stub.Request.StreamMatch != "all" && stub.Request.StreamMatch != "last" && stub.Request.StreamMatch != "any" {
+		errMsgs = append(errMsgs, "Request stream matching type can only be either 'all', 'last' or 'any'.")
+	}
+	if !stub.Request.IsStream() && stub.Request.StreamMatch != "" {
+		errMsgs = append(errMsgs, "Request stream can't be empty when a stream matching type is provided.")
 	}
 	return len(errMsgs) == 0, errMsgs
 }
@@ -157,8 +250,10 @@ func (stub *Stub) isValidResponse() (isValid bool, errMsgs []string) {
 		errMsgs = append(errMsgs, "Response can't be empty when stub's type is 'mock'.")
 		return false, errMsgs
 	}
-	if stub.Response.Type != "error" && stub.Response.Type != "success" {
-		errMsgs = append(errMsgs, "Response type can only be either 'error' or 'success'.")
+	switch stub.Response.Type {
+	case "error", "success", "stream", "conversation", "sequence":
+	default:
+		errMsgs = append(errMsgs, "Response type can only be either 'error', 'success', 'stream', 'conversation' or 'sequence'.")
 	}
 	if stub.Response.Type == "success" && stub.Response.Content == "" {
 		errMsgs = append(errMsgs, "Response content is mandatory when the response type is 'success'.")
@@ -166,9 +261,81 @@ func (stub *Stub) isValidResponse() (isValid bool, errMsgs []string) {
 	if stub.Response.Type == "error" && stub.Response.Error == nil {
 		errMsgs = append(errMsgs, "Response error is mandatory when the response type ir 'error'.")
 	}
+	if stub.Response.Type == "stream" && len(stub.Response.Stream) == 0 && stub.Response.Error == nil {
+		errMsgs = append(errMsgs, "Response stream or error is mandatory when the response type is 'stream'.")
+	}
+	if stub.Response.Type == "conversation" {
+		errMsgs = append(errMsgs, stub.Response.isValidConversation()...)
+	}
+	if stub.Response.Type == "sequence" {
+		errMsgs = append(errMsgs, stub.Response.isValidSequence()...)
+	}
+	errMsgs = append(errMsgs, stub.Response.Delay.isValid("Response delay")...)
+	errMsgs = append(errMsgs, isValidResponseMetadata(stub.Response.Headers, "Response header")...)
+	errMsgs = append(errMsgs, isValidResponseMetadata(stub.Response.Trailers, "Response trailer")...)
 	return len(errMsgs) == 0, errMsgs
 }
 
+func (response *StubResponse) isValidSequence() (errMsgs []string) {
+	if response.Sequence == nil || len(response.Sequence.Responses) == 0 {
+		return []string{"Response sequence is mandatory when the response type is 'sequence'."}
+	}
+	for i, sequenceResponse := range response.Sequence.Responses {
+		switch {
+		case sequenceResponse == nil:
+			errMsgs = append(errMsgs, fmt.Sprintf("Sequence response %d can't be empty.", i))
+		case sequenceResponse.Type == "success" && sequenceResponse.Content == "":
+			errMsgs = append(errMsgs, fmt.Sprintf("Sequence response %d: content is mandatory when the response type is 'success'.", i))
+		case sequenceResponse.Type == "error" && sequenceResponse.Error == nil:
+			errMsgs = append(errMsgs, fmt.Sprintf("Sequence response %d: error is mandatory when the response type is 'error'.", i))
+		case sequenceResponse.Type != "success" && sequenceResponse.Type != "error":
+			errMsgs = append(errMsgs, fmt.Sprintf("Sequence response %d: type can only be either 'error' or 'success'.", i))
+		default:
+			errMsgs = append(errMsgs, sequenceResponse.Delay.isValid(fmt.Sprintf("Sequence response %d delay", i))...)
+			errMsgs = append(errMsgs, isValidResponseMetadata(sequenceResponse.Headers, fmt.Sprintf("Sequence response %d header", i))...)
+			errMsgs = append(errMsgs, isValidResponseMetadata(sequenceResponse.Trailers, fmt.Sprintf("Sequence response %d trailer", i))...)
+		}
+	}
+	switch response.Sequence.Exhausted {
+	case "", "repeat", "cycle":
+	case "error":
+		if response.Sequence.Error == nil {
+			errMsgs = append(errMsgs, "Sequence error is mandatory when exhausted is 'error'.")
+		}
+	default:
+		errMsgs = append(errMsgs, "Sequence exhausted can only be either 'repeat', 'cycle' or 'error'.")
+	}
+	return errMsgs
+}
+
+func (response *StubResponse) isValidConversation() (errMsgs []string) {
+	if len(response.Conversation) == 0 {
+		errMsgs = append(errMsgs, "Response conversation is mandatory when the response type is 'conversation'.")
+	}
+	for i, step := range response.Conversation {
+		switch step.Type {
+		case "expect":
+			if step.Match != "exact" && step.Match != "partial" {
+				errMsgs = append(errMsgs, fmt.Sprintf("Conversation step %d: matching type can only be either 'exact' or 'partial'.", i))
+			}
+			if step.Content == "" {
+				errMsgs = append(errMsgs, fmt.Sprintf("Conversation step %d: content is mandatory when the step type is 'expect'.", i))
+			}
+		case "send":
+			if len(step.Send) == 0 {
+				errMsgs = append(errMsgs, fmt.Sprintf("Conversation step %d: messages to send are mandatory when the step type is 'send'.", i))
+			}
+		case "close":
+			if i != len(response.Conversation)-1 {
+				errMsgs = append(errMsgs, fmt.Sprintf("Conversation step %d: 'close' must be the last step of the conversation.", i))
+			}
+		default:
+			errMsgs = append(errMsgs, fmt.Sprintf("Conversation step %d: type can only be either 'expect', 'send' or 'close'.", i))
+		}
+	}
+	return errMsgs
+}
+
 func (stub *Stub) isValidForward() (isValid bool, errMsgs []string) {
 	if stub.Type != "forward" {
 		return true, nil
@@ -186,5 +353,6 @@ func (stub *Stub) isValidForward() (isValid bool, errMsgs []string) {
 	if stub.Forward.ServerAddress == "" {
 		errMsgs = append(errMsgs, "You must provide a server address for forwarding stub types.")
 	}
+	errMsgs = append(errMsgs, stub.Forward.isValid("Forward")...)
 	return len(errMsgs) == 0, errMsgs
 }
@@ -0,0 +1,87 @@
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+)
+
+// Verification selects the calls in the request journal made to a method with a request matching the one provided
+type Verification struct {
+	FullMethod string       `json:"fullMethod"`
+	Request    *StubRequest `json:"request,omitempty"` // nil matches any request
+}
+
+type VerificationResult struct {
+	Count int `json:"count"`
+}
+
+// CountCalls returns the number of calls in the journal matching the verification
+func CountCalls(journal RequestJournal, verification *Verification) int {
+	count := 0
+	for _, entry := range journal.Find(JournalFilter{FullMethod: verification.FullMethod}) {
+		if verification.Matches(entry) {
+			count++
+		}
+	}
+	return count
+}
+
+// Matches tells whether the call was made to the verification's method with a matching request and metadata.
+// The request of a client streaming call is matched as a stream when the verification's request has one.
+func (v *Verification) Matches(entry JournalEntry) bool {
+	if entry.FullMethod != v.FullMethod {
+		return false
+	}
+	if v.Request == nil {
+		return true
+	}
+	if !matchRequestMetadata(v.Request, metadata.MD(entry.Metadata)) {
+		return false
+	}
+	if v.Request.IsStream() {
+		requestsJson, err := splitJsonArray(entry.Request)
+		return err == nil && matchStream(v.Request, requestsJson)
+	}
+	return v.Request.matches(entry.FullMethod, string(entry.Request), metadata.MD(entry.Metadata))
+}
+
+func (v *Verification) IsValid() (isValid bool, errMsgs []string) {
+	if v.FullMethod == "" {
+		errMsgs = append(errMsgs, "Full method name can't be empty.")
+	}
+	if v.Request == nil {
+		return len(errMsgs) == 0, errMsgs // any request matches
+	}
+	if v.Request.Match != "exact" && v.Request.Match != "partial" && v.Request.Match != "jsonpath" && v.Request.Match != "cel" {
+		errMsgs = append(errMsgs, "Request matching type can only be either 'exact', 'partial', 'jsonpath' or 'cel'.")
+	}
+	if v.Request.Match == "jsonpath" {
+		errMsgs = append(errMsgs, v.Request.isValidPaths()...)
+	}
+	if v.Request.Match == "cel" {
+		request, err := findRequestDescriptor(v.FullMethod)
+		if err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("Request expression can't be checked: %s", err))
+		} else {
+			errMsgs = append(errMsgs, v.Request.isValidExpression(request)...)
+		}
+	}
+	errMsgs = append(errMsgs, v.Request.isValidMetadataMatch()...)
+	if v.Request.IsStream() && v.Request.StreamMatch != "all" && v.Request.StreamMatch != "last" && v.Request.StreamMatch != "any" {
+		errMsgs = append(errMsgs, "Request stream matching type can only be either 'all', 'last' or 'any'.")
+	}
+	return len(errMsgs) == 0, errMsgs
+}
+
+func splitJsonArray(content JsonString) ([]string, error) {
+	messages := make([]json.RawMessage, 0)
+	if err := json.Unmarshal([]byte(content), &messages); err != nil {
+		return nil, fmt.Errorf("could not read the stream of messages: %w", err)
+	}
+	messagesJson := make([]string, 0, len(messages))
+	for _, message := range messages {
+		messagesJson = append(messagesJson, string(message))
+	}
+	return messagesJson, nil
+}
@@ -0,0 +1,60 @@
+package stub
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newVerificationJournal() RequestJournal {
+	journal := NewInMemoryRequestJournal(10)
+	journal.Add(JournalEntry{FullMethod: "method1", Request: "{\"name\":\"a\",\"id\":1}", Metadata: map[string][]string{"tenant": {"x"}}})
+	journal.Add(JournalEntry{FullMethod: "method1", Request: "{\"name\":\"a\",\"id\":2}"})
+	journal.Add(JournalEntry{FullMethod: "method1", Request: "{\"name\":\"b\"}"})
+	journal.Add(JournalEntry{FullMethod: "method2", Request: "[{\"name\":\"a\"},{\"name\":\"b\"}]"})
+	return journal
+}
+
+func TestCountCalls(t *testing.T) {
+	journal := newVerificationJournal()
+
+	assert.Equal(t, 3, CountCalls(journal, &Verification{FullMethod: "method1"}))
+	assert.Equal(t, 2, CountCalls(journal, &Verification{
+		FullMethod: "method1",
+		Request:    &StubRequest{Match: "partial", Content: "{\"name\":\"a\"}"},
+	}))
+	assert.Equal(t, 1, CountCalls(journal, &Verification{
+		FullMethod: "method1",
+		Request:    &StubRequest{Match: "exact", Content: "{\"name\":\"a\",\"id\":2}"},
+	}))
+	assert.Equal(t, 1, CountCalls(journal, &Verification{
+		FullMethod: "method1",
+		Request:    &StubRequest{Match: "partial", Content: "{\"name\":\"a\"}", Metadata: map[string][]string{"tenant": {"x"}}},
+	}))
+	// any request made with the metadata, as sent by the remote client for a nil request
+	assert.Equal(t, 1, CountCalls(journal, &Verification{
+		FullMethod: "method1",
+		Request:    &StubRequest{Match: "partial", Content: "{}", Metadata: map[string][]string{"tenant": {"x"}}},
+	}))
+	assert.Equal(t, 0, CountCalls(journal, &Verification{
+		FullMethod: "method3",
+	}))
+}
+
+func TestCountCalls_Stream(t *testing.T) {
+	journal := newVerificationJournal()
+
+	assert.Equal(t, 1, CountCalls(journal, &Verification{
+		FullMethod: "method2",
+		Request:    &StubRequest{Match: "partial", Stream: []JsonString{"{\"name\":\"b\"}"}, StreamMatch: "last"},
+	}))
+	assert.Equal(t, 0, CountCalls(journal, &Verification{
+		FullMethod: "method2",
+		Request:    &StubRequest{Match: "partial", Stream: []JsonString{"{\"name\":\"a\"}"}, StreamMatch: "all"},
+	}))
+}
+
+func TestVerification_IsValid(t *testing.T) {
+	isValid, errMsgs := (&Verification{Request: &StubRequest{Match: "fuzzy"}}).IsValid()
+	assert.False(t, isValid)
+	assert.Equal(t, []string{"Full method name can't be empty.", "Request matching type can only be either 'exact', 'partial', 'jsonpath' or 'cel'."}, errMsgs)
+}